@@ -14,16 +14,25 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"math/rand"
+	"os"
 	"time"
 
+	clusternetutils "github.com/clusternet/clusternet/pkg/utils"
 	"github.com/spf13/cobra"
 	"k8s.io/klog/v2"
 
 	"github.com/clusternet/sample-controller/pkg/predictor"
 )
 
-var options predictor.PredictorOptions
+var (
+	options         predictor.PredictorOptions
+	simulateOptions predictor.SimulateOptions
+	replayOptions   predictor.ReplayOptions
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "predictor",
@@ -34,17 +43,70 @@ var rootCmd = &cobra.Command{
 			klog.Exit(err)
 		}
 
-		p, err := predictor.NewPredictorServer(options)
+		p, err := predictor.NewPredictorServer(options, predictor.NewRegistry())
+		if err != nil {
+			klog.Exit(err)
+		}
+
+		if err = p.Run(clusternetutils.GracefulStopWithContext()); err != nil {
+			klog.Exit(err)
+		}
+	},
+}
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Predict replicas against a captured cluster state",
+	Long: "Simulate predicts the max acceptable replicas of the requirements against the nodes and pods loaded " +
+		"from yaml or json files, without a live API server, and prints the result with the per-node breakdown",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(simulateOptions.ObjectPaths) == 0 {
+			klog.Exit("--objects is required")
+		}
+
+		result, err := predictor.Simulate(context.Background(), simulateOptions, predictor.NewRegistry(), os.Stdin)
 		if err != nil {
 			klog.Exit(err)
 		}
 
-		if err = p.Run(); err != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(result); err != nil {
 			klog.Exit(err)
 		}
 	},
 }
 
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Replay recorded predictions against the current plugins",
+	Long: "Replay predicts the requests recorded with --record-dir again against the snapshots recorded with them, " +
+		"and prints the difference from the recorded results, exiting with an error if any of them changed",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(replayOptions.RecordPaths) == 0 {
+			klog.Exit("--records is required")
+		}
+
+		results, err := predictor.Replay(context.Background(), replayOptions, predictor.NewRegistry())
+		if err != nil {
+			klog.Exit(err)
+		}
+
+		changed := 0
+		for _, result := range results {
+			if result.Diff == "" {
+				fmt.Printf("%s: unchanged\n", result.Path)
+				continue
+			}
+			changed++
+			fmt.Printf("%s: changed\n%s\n", result.Path, result.Diff)
+		}
+		if changed > 0 {
+			klog.Exitf("%d of %d predictions changed", changed, len(results))
+		}
+	},
+}
+
 func main() {
 	rand.Seed(time.Now().UnixNano())
 	if err := rootCmd.Execute(); err != nil {
@@ -56,4 +118,32 @@ func init() {
 	rootCmd.Flags().UintVar(&options.Port, "port", 80, "port of predictor listen")
 	rootCmd.Flags().StringVar(&options.MasterURL, "master", "", "kubernetes master url")
 	rootCmd.Flags().StringVar(&options.KubeconfigPath, "kubeconfig", "", "kubernetes cluster config path")
+	rootCmd.Flags().StringVar(&options.ClustersKubeconfig, "clusters-kubeconfig", "", "directory of a kubeconfig per cluster, or a kubeconfig of a context per cluster, to serve multiple clusters")
+	rootCmd.Flags().Int64Var(&options.MaxRequestBytes, "max-request-bytes", 1<<20, "max size in bytes of a request body")
+	rootCmd.Flags().StringVar(&options.ConfigFile, "config", "", "path of the predictor configuration file, which enables and configures plugins")
+	rootCmd.Flags().DurationVar(&options.ShutdownGracePeriod, "shutdown-grace-period", 20*time.Second, "how long to wait for in-flight requests when the predictor is stopped")
+	rootCmd.Flags().StringVar(&options.TLSCertFile, "tls-cert-file", "", "x509 certificate for https, reloaded when it changes on disk")
+	rootCmd.Flags().StringVar(&options.TLSPrivateKeyFile, "tls-private-key-file", "", "x509 private key matching --tls-cert-file")
+	rootCmd.Flags().StringVar(&options.ClientCAFile, "client-ca-file", "", "certificate bundle to verify client certificates, reloaded when it changes on disk")
+	rootCmd.Flags().BoolVar(&options.RequireClientCert, "require-client-cert", false, "reject the connections without a client certificate signed by --client-ca-file")
+	rootCmd.Flags().StringVar(&options.TokenAuthFile, "token-auth-file", "", "csv file of static bearer tokens to authenticate callers")
+	rootCmd.Flags().BoolVar(&options.AuthenticationTokenWebhook, "authentication-token-webhook", false, "authenticate bearer tokens with the TokenReview API")
+	rootCmd.Flags().StringVar(&options.AuthorizationMode, "authorization-mode", predictor.AuthorizationModeAlwaysAllow, "authorization mode of callers, AlwaysAllow or Webhook which uses the SubjectAccessReview API")
+	rootCmd.Flags().StringVar(&options.AuthorizationVerb, "authorization-verb", "create", "verb callers are authorized against in the Webhook authorization mode")
+	rootCmd.Flags().StringVar(&options.AuthorizationGroup, "authorization-group", "predictor.clusternet.io", "api group callers are authorized against in the Webhook authorization mode")
+	rootCmd.Flags().StringVar(&options.AuthorizationResource, "authorization-resource", "predictions", "resource callers are authorized against in the Webhook authorization mode")
+	rootCmd.Flags().StringVar(&options.RecordDir, "record-dir", "", "directory to record the predictions with their snapshots in, for replaying them later")
+	rootCmd.Flags().IntVar(&options.RecordMaxFiles, "record-max-files", 100, "number of the latest records kept in --record-dir")
+	rootCmd.Flags().DurationVar(&options.ReservationTTL, "reservation-ttl", 0, "how long the replicas predicted for requests with a reservationID are reserved, 0 disables reservations")
+	rootCmd.Flags().Int64Var(&options.ReservationMaxReplicas, "reservation-max-replicas", 0, "most replicas a single reservation holds, 0 for no limit")
+
+	rootCmd.AddCommand(simulateCmd)
+	simulateCmd.Flags().StringSliceVar(&simulateOptions.ObjectPaths, "objects", nil, "yaml or json files, or directories of them, holding the nodes, pods and other objects of the cluster")
+	simulateCmd.Flags().StringVar(&simulateOptions.RequirementsFile, "requirements", "-", "yaml or json file of the requirements, in the request body format of /accept, or - for stdin")
+	simulateCmd.Flags().StringVar(&simulateOptions.ConfigFile, "config", "", "path of the predictor configuration file, which enables and configures plugins")
+	simulateCmd.Flags().BoolVar(&simulateOptions.CheckLeases, "check-leases", false, "keep excluding the nodes with stale leases, which are usually stale in a captured state")
+
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().StringSliceVar(&replayOptions.RecordPaths, "records", nil, "records written with --record-dir, or directories of them")
+	replayCmd.Flags().StringVar(&replayOptions.ConfigFile, "config", "", "path of the predictor configuration file, which enables and configures plugins")
 }
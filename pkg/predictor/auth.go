@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/authenticatorfactory"
+	"k8s.io/apiserver/pkg/authentication/group"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	"k8s.io/apiserver/pkg/authentication/request/union"
+	"k8s.io/apiserver/pkg/authentication/token/tokenfile"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/authorization/authorizerfactory"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// AuthorizationModeAlwaysAllow allows all the requests.
+	AuthorizationModeAlwaysAllow = "AlwaysAllow"
+	// AuthorizationModeWebhook authorizes the requests with SubjectAccessReview.
+	AuthorizationModeWebhook = "Webhook"
+)
+
+const (
+	webhookTimeout  = 10 * time.Second
+	webhookCacheTTL = 10 * time.Second
+)
+
+// webhookRetryBackoff is the backoff of retrying TokenReview and SubjectAccessReview.
+var webhookRetryBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   1.5,
+	Jitter:   0.2,
+	Steps:    5,
+}
+
+// authorizationAttributes are the attributes a caller must be allowed to act with.
+type authorizationAttributes struct {
+	verb     string
+	group    string
+	resource string
+}
+
+// newAuthenticator returns the union of all the configured authenticators,
+// or nil if none is configured and every request is treated as anonymous.
+func newAuthenticator(options PredictorOptions, client kubernetes.Interface,
+	clientCA dynamiccertificates.CAContentProvider) (authenticator.Request, error) {
+	var authenticators []authenticator.Request
+	if options.TokenAuthFile != "" {
+		tokenAuth, err := tokenfile.NewCSV(options.TokenAuthFile)
+		if err != nil {
+			return nil, fmt.Errorf("error of load token auth file : %v", err)
+		}
+		authenticators = append(authenticators, bearertoken.New(tokenAuth))
+	}
+
+	if options.AuthenticationTokenWebhook || clientCA != nil {
+		config := authenticatorfactory.DelegatingAuthenticatorConfig{
+			TokenAccessReviewTimeout:           webhookTimeout,
+			WebhookRetryBackoff:                &webhookRetryBackoff,
+			CacheTTL:                           webhookCacheTTL,
+			ClientCertificateCAContentProvider: clientCA,
+		}
+		if options.AuthenticationTokenWebhook {
+			config.TokenAccessReviewClient = client.AuthenticationV1()
+		}
+		delegating, _, err := config.New()
+		if err != nil {
+			return nil, fmt.Errorf("error of create delegating authenticator : %v", err)
+		}
+		authenticators = append(authenticators, delegating)
+	}
+
+	if len(authenticators) == 0 {
+		return nil, nil
+	}
+	return group.NewAuthenticatedGroupAdder(union.New(authenticators...)), nil
+}
+
+// newAuthorizer returns the authorizer of the given mode, or nil if all the requests are allowed.
+func newAuthorizer(options PredictorOptions, client kubernetes.Interface) (authorizer.Authorizer, error) {
+	if options.AuthorizationMode != AuthorizationModeWebhook {
+		return nil, nil
+	}
+	config := authorizerfactory.DelegatingAuthorizerConfig{
+		SubjectAccessReviewClient: client.AuthorizationV1(),
+		AllowCacheTTL:             webhookCacheTTL,
+		DenyCacheTTL:              webhookCacheTTL,
+		WebhookRetryBackoff:       &webhookRetryBackoff,
+	}
+	authz, err := config.New()
+	if err != nil {
+		return nil, fmt.Errorf("error of create delegating authorizer : %v", err)
+	}
+	return authz, nil
+}
+
+// authorize authenticates the request and checks whether the caller may call the endpoint.
+// It writes the error response and returns false if the request should not be served.
+func (p *PredictorServer) authorize(w http.ResponseWriter, r *http.Request) bool {
+	var caller user.Info = &user.DefaultInfo{Name: user.Anonymous, Groups: []string{user.AllUnauthenticated}}
+	if p.authenticator != nil {
+		resp, ok, err := p.authenticator.AuthenticateRequest(r)
+		if err != nil || !ok {
+			if err != nil {
+				klog.V(4).Infof("error of authenticate request to %s : %v", r.URL.Path, err)
+			}
+			writeError(w, apierrors.NewUnauthorized("Unauthorized"))
+			return false
+		}
+		caller = resp.User
+	}
+
+	if p.authorizer == nil {
+		return true
+	}
+	// the endpoint is the name of the resource, so that callers could be limited to some endpoints by resourceNames
+	endpoint := strings.TrimPrefix(r.URL.Path, "/")
+	decision, reason, err := p.authorizer.Authorize(r.Context(), authorizer.AttributesRecord{
+		User:            caller,
+		Verb:            p.authz.verb,
+		APIGroup:        p.authz.group,
+		Resource:        p.authz.resource,
+		Name:            endpoint,
+		ResourceRequest: true,
+	})
+	if err != nil {
+		klog.Errorf("error of authorize %s to %s : %v", caller.GetName(), r.URL.Path, err)
+		writeError(w, apierrors.NewInternalError(err))
+		return false
+	}
+	if decision != authorizer.DecisionAllow {
+		klog.V(4).Infof("forbid %s to %s : %s", caller.GetName(), r.URL.Path, reason)
+		writeError(w, apierrors.NewForbidden(schema.GroupResource{Group: p.authz.group, Resource: p.authz.resource},
+			endpoint, fmt.Errorf("user %q cannot %s %s: %s", caller.GetName(), p.authz.verb, p.authz.resource, reason)))
+		return false
+	}
+	return true
+}
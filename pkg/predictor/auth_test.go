@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+func TestAuthorize(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "tokens.csv")
+	if err := ioutil.WriteFile(tokenFile, []byte("scheduler-token,clusternet-scheduler,1\nother-token,someone,2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	authn, err := newAuthenticator(PredictorOptions{TokenAuthFile: tokenFile}, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var gotAttrs authorizer.Attributes
+	authz := authorizer.AuthorizerFunc(func(_ context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+		gotAttrs = a
+		if a.GetUser().GetName() == "clusternet-scheduler" {
+			return authorizer.DecisionAllow, "", nil
+		}
+		return authorizer.DecisionNoOpinion, "not the scheduler", nil
+	})
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+	}{
+		{
+			name:     "no token",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "unknown token",
+			token:    "unknown-token",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "forbidden user",
+			token:    "other-token",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "allowed user",
+			token:    "scheduler-token",
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PredictorServer{
+				authenticator: authn,
+				authorizer:    authz,
+				authz:         authorizationAttributes{verb: "create", group: "predictor.clusternet.io", resource: "predictions"},
+			}
+			r := httptest.NewRequest(http.MethodPost, "/accept", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			if p.authorize(w, r) {
+				w.WriteHeader(http.StatusOK)
+			}
+			if w.Code != tt.wantCode {
+				t.Errorf("authorize() code = %d, want %d, body %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode == http.StatusOK && (gotAttrs.GetVerb() != "create" || gotAttrs.GetName() != "accept") {
+				t.Errorf("authorize() attributes = %s %s, want create accept", gotAttrs.GetVerb(), gotAttrs.GetName())
+			}
+		})
+	}
+}
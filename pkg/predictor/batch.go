@@ -0,0 +1,230 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+// BatchMaxAcceptableReplicas is a http handler predicting the max replicas of several requirements
+// against a single snapshot of the nodes and pods.
+func (p *PredictorServer) BatchMaxAcceptableReplicas(w http.ResponseWriter, r *http.Request) {
+	var batch BatchAcceptRequest
+	if err := p.decodeRequest(r, &batch); err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(batch.Items) == 0 {
+		writeError(w, apierrors.NewBadRequest("items are required"))
+		return
+	}
+
+	reservationIDs := sets.NewString()
+	for i := range batch.Items {
+		if id := batch.Items[i].ReservationID; id != "" {
+			reservationIDs.Insert(id)
+		}
+	}
+	if reservationIDs.Len() != 0 && p.reservations == nil {
+		writeError(w, errReservationsDisabled)
+		return
+	}
+
+	snapshot := p.cache.Snapshot()
+	if p.reservations != nil {
+		if reservationIDs.Len() != 0 {
+			p.reservations.predictMu.Lock()
+			defer p.reservations.predictMu.Unlock()
+		}
+		// the items see neither the reservations they replace nor each other's, unless they are joint
+		snapshot = p.reservations.assume(snapshot, reservationIDs)
+	}
+
+	resp := &BatchAcceptableReplicas{
+		APIVersion: PredictorAPIVersion,
+		Kind:       "BatchAcceptableReplicas",
+		Items:      make([]BatchAcceptableReplicasItem, 0, len(batch.Items)),
+	}
+	for i := range batch.Items {
+		item := &batch.Items[i]
+		if item.Replicas != nil && *item.Replicas < 0 {
+			err := apierrors.NewBadRequest(fmt.Sprintf("replicas %d must not be negative", *item.Replicas))
+			resp.Items = append(resp.Items, BatchAcceptableReplicasItem{ID: item.ID, Error: err.Error()})
+			continue
+		}
+		if err := p.resolvePriority(&item.MaxAcceptableReplicasRequest); err != nil {
+			resp.Items = append(resp.Items, BatchAcceptableReplicasItem{ID: item.ID, Error: err.Error()})
+			continue
+		}
+		if err := p.applyLimitRanges(&item.MaxAcceptableReplicasRequest); err != nil {
+			resp.Items = append(resp.Items, BatchAcceptableReplicasItem{ID: item.ID, Error: err.Error()})
+			continue
+		}
+		result, err := p.predict(r.Context(), &item.MaxAcceptableReplicasRequest, snapshot)
+		if err != nil {
+			klog.Errorf("error of predict replicas of batch item %d %q : %v", i, item.ID, err)
+			resp.Items = append(resp.Items, BatchAcceptableReplicasItem{ID: item.ID, Error: err.Error()})
+			continue
+		}
+		acceptable := newAcceptableReplicas(result)
+		// the item takes up its desired replicas of the predicted ones, or all of them
+		replicas := result.Replicas
+		if item.Replicas != nil && *item.Replicas < replicas {
+			replicas = *item.Replicas
+		}
+		if item.ReservationID != "" {
+			setReservation(acceptable, p.reservations.reserve(item.ReservationID, &item.MaxAcceptableReplicasRequest, placeReplicas(snapshot, result, replicas)))
+		}
+		resp.Items = append(resp.Items, BatchAcceptableReplicasItem{ID: item.ID, Result: acceptable})
+
+		if batch.Joint {
+			snapshot = assumeReplicas(snapshot, &item.MaxAcceptableReplicasRequest, i, result, replicas)
+		}
+	}
+
+	w.Header().Set("Content-Type", contentTypeJSON)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		klog.Error(err)
+	}
+}
+
+// assumeReplicas returns a snapshot of the nodes with the given replicas of the item placed on them.
+func assumeReplicas(snapshot *framework.Snapshot, require *MaxAcceptableReplicasRequest, index int, result *framework.Result, replicas int64) *framework.Snapshot {
+	pod := newAssumedPod(require, fmt.Sprintf("batch-%d", index))
+	assumed := make(map[string][]framework.AssumedReplicas)
+	for name, replicas := range placeReplicas(snapshot, result, replicas) {
+		assumed[name] = append(assumed[name], framework.AssumedReplicas{Pod: pod, Replicas: replicas})
+	}
+	return snapshot.WithAssumedReplicas(assumed)
+}
+
+// placeReplicas places the replicas, at most the predicted ones, over the nodes of the snapshot. The
+// replicas are spread evenly over the nodes within their estimates, the same as kube-scheduler favors the
+// least loaded nodes, and a node never holds more pods than its allocatable pods.
+func placeReplicas(snapshot *framework.Snapshot, result *framework.Result, replicas int64) map[string]int64 {
+	if replicas > result.Replicas {
+		replicas = result.Replicas
+	}
+	placement := spreadReplicas(replicas, result.NodeReplicas)
+	for name, replicas := range placement {
+		nodeInfo := snapshot.Get(name)
+		if nodeInfo == nil {
+			delete(placement, name)
+			continue
+		}
+		if allocatable := nodeInfo.Node().Status.Allocatable.Pods().Value(); replicas > allocatable {
+			placement[name] = allocatable
+		}
+		if placement[name] <= 0 {
+			delete(placement, name)
+		}
+	}
+	return placement
+}
+
+// spreadReplicas places the replicas over the nodes, so that the node holding the most replicas
+// holds as few as possible. Ties are broken by node name.
+func spreadReplicas(replicas int64, nodeReplicas map[string]int64) map[string]int64 {
+	names := make([]string, 0, len(nodeReplicas))
+	var max int64
+	for name, estimate := range nodeReplicas {
+		names = append(names, name)
+		if estimate > max {
+			max = estimate
+		}
+	}
+	sort.Strings(names)
+
+	// placedBelow returns the replicas placed when every node holds at most level replicas
+	placedBelow := func(level int64) int64 {
+		var sum int64
+		for _, estimate := range nodeReplicas {
+			if estimate > level {
+				estimate = level
+			}
+			if sum += estimate; sum < 0 || sum >= replicas {
+				return replicas
+			}
+		}
+		return sum
+	}
+	// find the lowest level that places all the replicas
+	low, high := int64(0), max
+	for low < high {
+		mid := low + (high-low)/2
+		if placedBelow(mid) >= replicas {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+
+	placement := make(map[string]int64, len(names))
+	remaining := replicas
+	for _, name := range names {
+		placed := nodeReplicas[name]
+		if placed > low-1 {
+			placed = low - 1
+		}
+		if placed > 0 {
+			placement[name] = placed
+			remaining -= placed
+		}
+	}
+	for _, name := range names {
+		if remaining <= 0 {
+			break
+		}
+		if nodeReplicas[name] >= low {
+			placement[name]++
+			remaining--
+		}
+	}
+	return placement
+}
+
+// newAssumedPod returns a pod standing for every replica of the request, wherever they are placed.
+func newAssumedPod(require *MaxAcceptableReplicasRequest, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: require.Namespace,
+			Name:      name,
+			Labels:    require.PodLabels,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:      "assumed",
+				Resources: require.Resources,
+			}},
+			NodeSelector:              require.NodeSelector,
+			Affinity:                  require.Affinity,
+			Tolerations:               require.Tolerations,
+			TopologySpreadConstraints: require.TopologySpreadConstraints,
+			Priority:                  require.Priority,
+			PriorityClassName:         require.PriorityClassName,
+			PreemptionPolicy:          require.PreemptionPolicy,
+		},
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+	"github.com/clusternet/sample-controller/pkg/predictor/plugins"
+)
+
+func TestSpreadReplicas(t *testing.T) {
+	tests := []struct {
+		name         string
+		replicas     int64
+		nodeReplicas map[string]int64
+		want         map[string]int64
+	}{
+		{
+			name:         "no replica",
+			nodeReplicas: map[string]int64{"node-1": 3},
+			want:         map[string]int64{},
+		},
+		{
+			name:         "evenly spread",
+			replicas:     5,
+			nodeReplicas: map[string]int64{"node-1": 3, "node-2": 3, "node-3": 3},
+			want:         map[string]int64{"node-1": 2, "node-2": 2, "node-3": 1},
+		},
+		{
+			name:         "bounded by the estimates",
+			replicas:     6,
+			nodeReplicas: map[string]int64{"node-1": 1, "node-2": 5, "node-3": 0},
+			want:         map[string]int64{"node-1": 1, "node-2": 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spreadReplicas(tt.replicas, tt.nodeReplicas); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("spreadReplicas() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchMaxAcceptableReplicas(t *testing.T) {
+	p := newTestServer()
+	p.synced = 1
+	for _, name := range []string{"node-1", "node-2"} {
+		p.cache.AddNode(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:  resource.MustParse("4"),
+				corev1.ResourcePods: resource.MustParse("110"),
+			}},
+		})
+	}
+	var err error
+	p.framework, err = framework.NewFramework(plugins.NewInTreeRegistry(), framework.Plugins{
+		Filter:   []string{plugins.InterPodAffinityName},
+		Estimate: []string{plugins.NodeResourcesFitName},
+	}, nil, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := `[
+		{"id": "a", "resources": {"requests": {"cpu": "1500m"}}},
+		{"id": "b", "resources": {"requests": {"cpu": "1"}}},
+		{"affinity": {"podAntiAffinity": {"requiredDuringSchedulingIgnoredDuringExecution": [
+			{"labelSelector": {"matchExpressions": [{"key": "app", "operator": "Bogus"}]}, "topologyKey": "zone"}
+		]}}}
+	]`
+	// withReplicas sets the desired replicas of the first item
+	withReplicas := func(replicas string) string {
+		return strings.Replace(items, `{"id": "a", `, `{"id": "a", "replicas": `+replicas+`, `, 1)
+	}
+	tests := []struct {
+		name         string
+		body         string
+		wantCode     int
+		wantReplicas []int64
+	}{
+		{
+			name:         "independent items",
+			body:         `{"items": ` + items + `}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: []int64{4, 8, -1},
+		},
+		{
+			name:         "joint items",
+			body:         `{"joint": true, "items": ` + items + `}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: []int64{4, 2, -1},
+		},
+		{
+			// the replica of the first item shares node-1 with the second item
+			name:         "joint items with desired replicas",
+			body:         `{"joint": true, "items": ` + withReplicas("1") + `}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: []int64{4, 6, -1},
+		},
+		{
+			name:         "desired replicas beyond the prediction",
+			body:         `{"joint": true, "items": ` + withReplicas("10") + `}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: []int64{4, 2, -1},
+		},
+		{
+			name:         "negative desired replicas",
+			body:         `{"joint": true, "items": ` + withReplicas("-1") + `}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: []int64{-1, 8, -1},
+		},
+		{
+			name:     "no item",
+			body:     `{"items": []}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			p.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/accept-batch", strings.NewReader(tt.body)))
+			if w.Code != tt.wantCode {
+				t.Fatalf("BatchMaxAcceptableReplicas() code = %d, want %d, body %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp BatchAcceptableReplicas
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []int64
+			for _, item := range resp.Items {
+				if item.Result == nil {
+					got = append(got, -1)
+					continue
+				}
+				got = append(got, item.Result.MaxAcceptableReplicas)
+			}
+			if !reflect.DeepEqual(got, tt.wantReplicas) {
+				t.Errorf("BatchMaxAcceptableReplicas() replicas = %v, want %v", got, tt.wantReplicas)
+			}
+			if resp.Items[0].ID != "a" || resp.Items[2].Error == "" {
+				t.Errorf("BatchMaxAcceptableReplicas() items = %+v, want ids and errors of the items", resp.Items)
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+// Cache keeps the NodeInfos of a cluster up to date from the events of nodes and pods, so that
+// predictions do not walk all the nodes and pods of a large cluster on every request.
+// Only the nodes changed since the last snapshot are rebuilt when taking a snapshot.
+type Cache struct {
+	mu sync.Mutex
+
+	nodes map[string]*nodeItem
+	// podNodes maps the key of a pod to the node it is bound to.
+	podNodes map[string]string
+
+	// labelIndex is copied on write once published by a snapshot. rootOwned, ownedKeys and ownedValues
+	// record the maps and slices written since the last snapshot, which are safe to change in place.
+	labelIndex  framework.LabelIndex
+	rootOwned   bool
+	ownedKeys   map[string]bool
+	ownedValues map[labelPair]bool
+
+	// names are the sorted names of the known nodes, rebuilt when nodes are added or deleted.
+	names        []string
+	namesChanged bool
+
+	snapshot *framework.Snapshot
+	changed  bool
+	// changedNodes are the names of the nodes changed since the last snapshot.
+	changedNodes map[string]bool
+}
+
+// nodeItem holds a node with the pods bound to it. The node is nil when the pods arrive before their node.
+type nodeItem struct {
+	node *corev1.Node
+	pods map[string]*corev1.Pod
+	// info is nil when the node or its pods changed since the last snapshot
+	info *framework.NodeInfo
+}
+
+type labelPair struct {
+	key   string
+	value string
+}
+
+// New returns an empty Cache.
+func New() *Cache {
+	return &Cache{
+		nodes:        make(map[string]*nodeItem),
+		podNodes:     make(map[string]string),
+		labelIndex:   make(framework.LabelIndex),
+		rootOwned:    true,
+		ownedKeys:    make(map[string]bool),
+		ownedValues:  make(map[labelPair]bool),
+		changed:      true,
+		changedNodes: make(map[string]bool),
+	}
+}
+
+// NodeEventHandler returns the handler keeping the cache up to date with the events of a node informer.
+func (c *Cache) NodeEventHandler() toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if node, ok := obj.(*corev1.Node); ok {
+				c.AddNode(node)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if node, ok := newObj.(*corev1.Node); ok {
+				c.AddNode(node)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if node, ok := obj.(*corev1.Node); ok {
+				c.DeleteNode(node)
+			}
+		},
+	}
+}
+
+// PodEventHandler returns the handler keeping the cache up to date with the events of a pod informer.
+func (c *Cache) PodEventHandler() toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				c.AddPod(pod)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if pod, ok := newObj.(*corev1.Pod); ok {
+				c.AddPod(pod)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pod, ok := obj.(*corev1.Pod); ok {
+				c.DeletePod(pod)
+			}
+		},
+	}
+}
+
+// AddNode adds or updates the node.
+func (c *Cache) AddNode(node *corev1.Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.nodes[node.Name]
+	if !ok {
+		item = &nodeItem{pods: make(map[string]*corev1.Pod)}
+		c.nodes[node.Name] = item
+	}
+	var oldLabels map[string]string
+	if item.node == nil {
+		c.namesChanged = true
+	} else {
+		oldLabels = item.node.Labels
+	}
+	c.updateLabels(node.Name, oldLabels, node.Labels)
+	item.node = node
+	c.invalidate(node.Name, item)
+}
+
+// DeleteNode deletes the node. Its pods are kept until they are deleted, in case the node is recreated.
+func (c *Cache) DeleteNode(node *corev1.Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.nodes[node.Name]
+	if !ok || item.node == nil {
+		return
+	}
+	c.updateLabels(node.Name, item.node.Labels, nil)
+	item.node = nil
+	c.invalidate(node.Name, item)
+	if len(item.pods) == 0 {
+		delete(c.nodes, node.Name)
+	}
+	c.namesChanged = true
+}
+
+// AddPod adds or updates the pod. Pods not bound to any node are ignored.
+func (c *Cache) AddPod(pod *corev1.Pod) {
+	key, err := toolscache.MetaNamespaceKeyFunc(pod)
+	if err != nil {
+		klog.Errorf("error of get key of pod : %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// a pod is bound once, but the update may still be observed as a move after a missed event
+	if nodeName, ok := c.podNodes[key]; ok && nodeName != pod.Spec.NodeName {
+		c.removePod(key, nodeName)
+	}
+	if pod.Spec.NodeName == "" {
+		return
+	}
+
+	item, ok := c.nodes[pod.Spec.NodeName]
+	if !ok {
+		item = &nodeItem{pods: make(map[string]*corev1.Pod)}
+		c.nodes[pod.Spec.NodeName] = item
+	}
+	item.pods[key] = pod
+	c.invalidate(pod.Spec.NodeName, item)
+	c.podNodes[key] = pod.Spec.NodeName
+}
+
+// DeletePod deletes the pod.
+func (c *Cache) DeletePod(pod *corev1.Pod) {
+	key, err := toolscache.MetaNamespaceKeyFunc(pod)
+	if err != nil {
+		klog.Errorf("error of get key of pod : %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if nodeName, ok := c.podNodes[key]; ok {
+		c.removePod(key, nodeName)
+	}
+}
+
+func (c *Cache) removePod(key, nodeName string) {
+	delete(c.podNodes, key)
+	item, ok := c.nodes[nodeName]
+	if !ok {
+		return
+	}
+	delete(item.pods, key)
+	c.invalidate(nodeName, item)
+	if item.node == nil && len(item.pods) == 0 {
+		delete(c.nodes, nodeName)
+	}
+}
+
+// invalidate marks the node changed, so that it is rebuilt by the next snapshot.
+func (c *Cache) invalidate(name string, item *nodeItem) {
+	item.info = nil
+	c.changedNodes[name] = true
+	c.changed = true
+}
+
+// Snapshot returns an immutable snapshot of the cached nodes. The snapshot is shared by the callers
+// until the cache changes.
+func (c *Cache) Snapshot() *framework.Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.changed {
+		return c.snapshot
+	}
+
+	if c.namesChanged {
+		c.names = c.names[:0]
+		for name, item := range c.nodes {
+			if item.node != nil {
+				c.names = append(c.names, name)
+			}
+		}
+		sort.Strings(c.names)
+		c.namesChanged = false
+	}
+
+	nodeInfos := make([]*framework.NodeInfo, 0, len(c.names))
+	for _, name := range c.names {
+		item := c.nodes[name]
+		if item.info == nil {
+			pods := make([]*corev1.Pod, 0, len(item.pods))
+			for _, pod := range item.pods {
+				pods = append(pods, pod)
+			}
+			// keep the order of pods stable across the rebuilds of a node
+			sort.Slice(pods, func(i, j int) bool {
+				if pods[i].Namespace != pods[j].Namespace {
+					return pods[i].Namespace < pods[j].Namespace
+				}
+				return pods[i].Name < pods[j].Name
+			})
+			item.info = framework.NewNodeInfo(item.node, pods...)
+		}
+		nodeInfos = append(nodeInfos, item.info)
+	}
+
+	// the label index is published, so that it must be copied on the next write
+	c.rootOwned = false
+	c.ownedKeys = make(map[string]bool)
+	c.ownedValues = make(map[labelPair]bool)
+
+	if c.snapshot == nil {
+		c.snapshot = framework.NewSnapshot(nodeInfos, c.labelIndex)
+	} else {
+		changedNodes := make([]string, 0, len(c.changedNodes))
+		for name := range c.changedNodes {
+			changedNodes = append(changedNodes, name)
+		}
+		c.snapshot = c.snapshot.Update(nodeInfos, c.labelIndex, changedNodes)
+	}
+	c.changed = false
+	c.changedNodes = make(map[string]bool)
+	return c.snapshot
+}
+
+// updateLabels updates the label index with the labels of the node changing from oldLabels to newLabels.
+func (c *Cache) updateLabels(name string, oldLabels, newLabels map[string]string) {
+	for key, value := range oldLabels {
+		if newValue, ok := newLabels[key]; !ok || newValue != value {
+			c.removeLabel(name, key, value)
+		}
+	}
+	for key, value := range newLabels {
+		if oldValue, ok := oldLabels[key]; !ok || oldValue != value {
+			c.addLabel(name, key, value)
+		}
+	}
+}
+
+func (c *Cache) addLabel(name, key, value string) {
+	names := c.ownedNames(key, value)
+	i := sort.SearchStrings(names, name)
+	if i < len(names) && names[i] == name {
+		return
+	}
+	names = append(names, "")
+	copy(names[i+1:], names[i:])
+	names[i] = name
+	c.labelIndex[key][value] = names
+}
+
+func (c *Cache) removeLabel(name, key, value string) {
+	if _, ok := c.labelIndex[key][value]; !ok {
+		return
+	}
+	names := c.ownedNames(key, value)
+	i := sort.SearchStrings(names, name)
+	if i == len(names) || names[i] != name {
+		return
+	}
+	names = append(names[:i], names[i+1:]...)
+	if len(names) != 0 {
+		c.labelIndex[key][value] = names
+		return
+	}
+	delete(c.labelIndex[key], value)
+	if len(c.labelIndex[key]) == 0 {
+		delete(c.labelIndex, key)
+		delete(c.ownedKeys, key)
+	}
+	delete(c.ownedValues, labelPair{key: key, value: value})
+}
+
+// ownedNames returns the names of the nodes with the label, copying the maps and the slice leading
+// to them if they are shared with a published snapshot.
+func (c *Cache) ownedNames(key, value string) []string {
+	if !c.rootOwned {
+		root := make(framework.LabelIndex, len(c.labelIndex))
+		for k, values := range c.labelIndex {
+			root[k] = values
+		}
+		c.labelIndex = root
+		c.rootOwned = true
+	}
+	if !c.ownedKeys[key] {
+		values := make(map[string][]string, len(c.labelIndex[key]))
+		for v, names := range c.labelIndex[key] {
+			values[v] = names
+		}
+		c.labelIndex[key] = values
+		c.ownedKeys[key] = true
+	}
+	pair := labelPair{key: key, value: value}
+	names := c.labelIndex[key][value]
+	if !c.ownedValues[pair] {
+		names = append(make([]string, 0, len(names)+1), names...)
+		c.labelIndex[key][value] = names
+		c.ownedValues[pair] = true
+	}
+	return names
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+func newTestNode(name, zone string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{corev1.LabelTopologyZone: zone}},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:  resource.MustParse("4"),
+			corev1.ResourcePods: resource.MustParse("110"),
+		}},
+	}
+}
+
+func newTestPod(name, nodeName, cpu string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Name:      "app",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+			}},
+		},
+	}
+}
+
+// describeSnapshot returns the pods of every node and the nodes of every zone in the snapshot.
+func describeSnapshot(snapshot *framework.Snapshot) (map[string][]string, map[string][]string) {
+	pods := make(map[string][]string)
+	for _, nodeInfo := range snapshot.List() {
+		names := []string{}
+		for _, pod := range nodeInfo.Pods() {
+			names = append(names, pod.Name)
+		}
+		pods[nodeInfo.Node().Name] = names
+	}
+	zones := make(map[string][]string)
+	for _, zone := range []string{"zone-a", "zone-b"} {
+		for _, nodeInfo := range snapshot.NodesWithLabel(corev1.LabelTopologyZone, zone) {
+			zones[zone] = append(zones[zone], nodeInfo.Node().Name)
+		}
+	}
+	return pods, zones
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	tests := []struct {
+		name      string
+		events    func(c *Cache)
+		wantPods  map[string][]string
+		wantZones map[string][]string
+	}{
+		{
+			name: "nodes and pods",
+			events: func(c *Cache) {
+				c.AddNode(newTestNode("node-2", "zone-a"))
+				c.AddNode(newTestNode("node-1", "zone-a"))
+				c.AddPod(newTestPod("pod-b", "node-1", "1"))
+				c.AddPod(newTestPod("pod-a", "node-1", "1"))
+				c.AddPod(newTestPod("pending", "", "1"))
+			},
+			wantPods:  map[string][]string{"node-1": {"pod-a", "pod-b"}, "node-2": {}},
+			wantZones: map[string][]string{"zone-a": {"node-1", "node-2"}},
+		},
+		{
+			name: "pods before their node",
+			events: func(c *Cache) {
+				c.AddPod(newTestPod("pod-a", "node-1", "1"))
+				c.AddPod(newTestPod("pod-b", "node-2", "1"))
+				c.AddNode(newTestNode("node-1", "zone-a"))
+			},
+			wantPods:  map[string][]string{"node-1": {"pod-a"}},
+			wantZones: map[string][]string{"zone-a": {"node-1"}},
+		},
+		{
+			name: "pod bound and deleted",
+			events: func(c *Cache) {
+				c.AddNode(newTestNode("node-1", "zone-a"))
+				c.AddNode(newTestNode("node-2", "zone-b"))
+				c.AddPod(newTestPod("pod-a", "", "1"))
+				c.AddPod(newTestPod("pod-a", "node-2", "1"))
+				c.AddPod(newTestPod("pod-b", "node-1", "1"))
+				c.DeletePod(newTestPod("pod-b", "node-1", "1"))
+			},
+			wantPods:  map[string][]string{"node-1": {}, "node-2": {"pod-a"}},
+			wantZones: map[string][]string{"zone-a": {"node-1"}, "zone-b": {"node-2"}},
+		},
+		{
+			name: "node relabeled and deleted",
+			events: func(c *Cache) {
+				c.AddNode(newTestNode("node-1", "zone-a"))
+				c.AddNode(newTestNode("node-2", "zone-a"))
+				c.AddNode(newTestNode("node-3", "zone-a"))
+				c.AddNode(newTestNode("node-1", "zone-b"))
+				c.DeleteNode(newTestNode("node-3", "zone-a"))
+			},
+			wantPods:  map[string][]string{"node-1": {}, "node-2": {}},
+			wantZones: map[string][]string{"zone-a": {"node-2"}, "zone-b": {"node-1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			tt.events(c)
+			gotPods, gotZones := describeSnapshot(c.Snapshot())
+			if !reflect.DeepEqual(gotPods, tt.wantPods) {
+				t.Errorf("Snapshot() pods = %v, want %v", gotPods, tt.wantPods)
+			}
+			if !reflect.DeepEqual(gotZones, tt.wantZones) {
+				t.Errorf("Snapshot() zones = %v, want %v", gotZones, tt.wantZones)
+			}
+		})
+	}
+}
+
+func TestCacheSnapshotIsImmutable(t *testing.T) {
+	c := New()
+	c.AddNode(newTestNode("node-1", "zone-a"))
+	c.AddNode(newTestNode("node-2", "zone-a"))
+	c.AddPod(newTestPod("pod-a", "node-1", "1"))
+	old := c.Snapshot()
+	if c.Snapshot() != old {
+		t.Errorf("Snapshot() is rebuilt without any change")
+	}
+
+	c.AddNode(newTestNode("node-1", "zone-b"))
+	c.AddNode(newTestNode("node-3", "zone-a"))
+	c.AddPod(newTestPod("pod-b", "node-1", "2"))
+	handler := c.NodeEventHandler()
+	handler.OnDelete(toolscache.DeletedFinalStateUnknown{Key: "node-2", Obj: newTestNode("node-2", "zone-a")})
+
+	gotPods, gotZones := describeSnapshot(old)
+	if want := map[string][]string{"node-1": {"pod-a"}, "node-2": {}}; !reflect.DeepEqual(gotPods, want) {
+		t.Errorf("old Snapshot() pods = %v, want %v", gotPods, want)
+	}
+	if want := map[string][]string{"zone-a": {"node-1", "node-2"}}; !reflect.DeepEqual(gotZones, want) {
+		t.Errorf("old Snapshot() zones = %v, want %v", gotZones, want)
+	}
+	free := old.Get("node-1").Free[corev1.ResourceCPU]
+	if free.Cmp(resource.MustParse("3")) != 0 {
+		t.Errorf("old Snapshot() free cpu of node-1 = %s, want 3", free.String())
+	}
+
+	gotPods, gotZones = describeSnapshot(c.Snapshot())
+	if want := map[string][]string{"node-1": {"pod-a", "pod-b"}, "node-3": {}}; !reflect.DeepEqual(gotPods, want) {
+		t.Errorf("Snapshot() pods = %v, want %v", gotPods, want)
+	}
+	if want := map[string][]string{"zone-a": {"node-3"}, "zone-b": {"node-1"}}; !reflect.DeepEqual(gotZones, want) {
+		t.Errorf("Snapshot() zones = %v, want %v", gotZones, want)
+	}
+	free = c.Snapshot().Get("node-1").Free[corev1.ResourceCPU]
+	if free.Cmp(resource.MustParse("1")) != 0 {
+		t.Errorf("Snapshot() free cpu of node-1 = %s, want 1", free.String())
+	}
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/metrics"
+)
+
+// ClusterHeader names the cluster a request to /accept, /accept-batch, /unschedul or /release is routed to,
+// when the predictor serves multiple clusters.
+const ClusterHeader = "X-Predictor-Cluster"
+
+// clusterReloadPeriod is how often the kubeconfigs of the clusters are reloaded.
+const clusterReloadPeriod = 30 * time.Second
+
+// clusterSet is the clusters served by a predictor, loaded from a directory of kubeconfigs,
+// or from the contexts of a kubeconfig.
+type clusterSet struct {
+	source    string
+	newServer func(name string, restConfig *rest.Config) (*PredictorServer, error)
+
+	mu       sync.RWMutex
+	clusters map[string]*cluster
+}
+
+// cluster is a cluster being served.
+type cluster struct {
+	server  *PredictorServer
+	handler http.Handler
+	// config is the kubeconfig of the cluster, minified to its context, to find out whether it changes
+	config *clientcmdapi.Config
+	stopCh chan struct{}
+}
+
+func newClusterSet(source string, newServer func(name string, restConfig *rest.Config) (*PredictorServer, error)) *clusterSet {
+	return &clusterSet{
+		source:    source,
+		newServer: newServer,
+		clusters:  make(map[string]*cluster),
+	}
+}
+
+// run reloads the clusters until stopCh is closed, and then stops all of them.
+func (s *clusterSet) run(stopCh <-chan struct{}) {
+	wait.Until(func() {
+		if err := s.reload(); err != nil {
+			klog.Errorf("error of reload clusters : %v", err)
+		}
+	}, clusterReloadPeriod, stopCh)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, c := range s.clusters {
+		close(c.stopCh)
+		delete(s.clusters, name)
+	}
+}
+
+// reload loads the kubeconfigs, starts the new clusters, stops the removed ones, and restarts the
+// clusters whose kubeconfig has changed. A cluster whose kubeconfig fails to load is kept as it is.
+func (s *clusterSet) reload() error {
+	configs, failed, err := loadKubeconfigs(s.source)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, c := range s.clusters {
+		if failed.Has(name) {
+			continue
+		}
+		if config, ok := configs[name]; ok && reflect.DeepEqual(config, c.config) {
+			continue
+		}
+		close(c.stopCh)
+		delete(s.clusters, name)
+		metrics.ForgetCluster(name)
+		klog.Infof("cluster %s is stopped", name)
+	}
+	for name, config := range configs {
+		if _, ok := s.clusters[name]; ok {
+			continue
+		}
+		restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			klog.Errorf("error of create kubernetes restConfig of cluster %s : %v", name, err)
+			continue
+		}
+		server, err := s.newServer(name, restConfig)
+		if err != nil {
+			klog.Errorf("error of create predictor of cluster %s : %v", name, err)
+			continue
+		}
+		c := &cluster{
+			server:  server,
+			handler: server.Handler(),
+			config:  config,
+			stopCh:  make(chan struct{}),
+		}
+		server.start(c.stopCh)
+		s.clusters[name] = c
+		klog.Infof("cluster %s is started", name)
+	}
+	return nil
+}
+
+// loadKubeconfigs loads the kubeconfig of every cluster, minified to the context of the cluster.
+// A directory holds a kubeconfig per cluster, named by the file name without its extension and using
+// its current context, while a file holds a context per cluster, named by the context. The names of
+// the clusters failing to load are returned as well.
+func loadKubeconfigs(source string) (map[string]*clientcmdapi.Config, sets.String, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error of load kubeconfigs : %v", err)
+	}
+
+	configs := make(map[string]*clientcmdapi.Config)
+	failed := sets.NewString()
+	if !info.IsDir() {
+		kubeconfig, err := loadKubeconfigFile(source)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error of load kubeconfig %s : %v", source, err)
+		}
+		for name := range kubeconfig.Contexts {
+			if !isValidClusterName(name) {
+				klog.Warningf("skip context %q of %s, which is not a valid cluster name", name, source)
+				continue
+			}
+			config := kubeconfig.DeepCopy()
+			config.CurrentContext = name
+			if err = clientcmdapi.MinifyConfig(config); err != nil {
+				klog.Errorf("error of load context %s of %s : %v", name, source, err)
+				failed.Insert(name)
+				continue
+			}
+			configs[name] = config
+		}
+		return configs, failed, nil
+	}
+
+	infos, err := ioutil.ReadDir(source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error of load kubeconfigs under %s : %v", source, err)
+	}
+	for _, info := range infos {
+		// files mounted from secrets and configmaps are linked to hidden directories
+		if strings.HasPrefix(info.Name(), ".") || info.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
+		if !isValidClusterName(name) {
+			klog.Warningf("skip kubeconfig %s, whose name is not a valid cluster name", info.Name())
+			continue
+		}
+		file := filepath.Join(source, info.Name())
+		config, err := loadKubeconfigFile(file)
+		if err == nil {
+			err = clientcmdapi.MinifyConfig(config)
+		}
+		if err != nil {
+			klog.Errorf("error of load kubeconfig %s : %v", file, err)
+			failed.Insert(name)
+			continue
+		}
+		configs[name] = config
+	}
+	return configs, failed, nil
+}
+
+// loadKubeconfigFile loads a kubeconfig, resolving the files it refers to relative to itself.
+func loadKubeconfigFile(file string) (*clientcmdapi.Config, error) {
+	config, err := clientcmd.LoadFromFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err = clientcmd.ResolveLocalPaths(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// isValidClusterName checks whether the name could be a segment of the paths of the cluster.
+func isValidClusterName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
+// get returns the cluster of the name, or nil if it is not served.
+func (s *clusterSet) get(name string) *cluster {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.clusters[name]
+}
+
+// routeByPath serves the requests to /clusters/<cluster>/<endpoint> with the endpoint of the cluster.
+func (s *clusterSet) routeByPath(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/clusters/"), "/", 2)
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	s.serve(w, r, parts[0], "/"+parts[1])
+}
+
+// routeByHeader serves the requests with the endpoint of the cluster named by the ClusterHeader.
+func (s *clusterSet) routeByHeader(w http.ResponseWriter, r *http.Request) {
+	name := r.Header.Get(ClusterHeader)
+	if name == "" {
+		writeError(w, apierrors.NewBadRequest(fmt.Sprintf("cluster is required, either by the path /clusters/<cluster>%s or by the %s header",
+			r.URL.Path, ClusterHeader)))
+		return
+	}
+	s.serve(w, r, name, r.URL.Path)
+}
+
+// serve serves the request with the handler of the cluster, as a request to the path.
+func (s *clusterSet) serve(w http.ResponseWriter, r *http.Request, name, path string) {
+	c := s.get(name)
+	if c == nil {
+		writeError(w, apierrors.NewNotFound(schema.GroupResource{Resource: "clusters"}, name))
+		return
+	}
+	routed := new(http.Request)
+	*routed = *r
+	routed.URL = new(url.URL)
+	*routed.URL = *r.URL
+	routed.URL.Path = path
+	routed.URL.RawPath = ""
+	c.handler.ServeHTTP(w, routed)
+}
+
+// List is a http handler that reports the clusters and whether each of them is ready.
+func (s *clusterSet) List(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.clusters))
+	servers := make(map[string]*PredictorServer, len(s.clusters))
+	for name, c := range s.clusters {
+		names = append(names, name)
+		servers[name] = c.server
+	}
+	s.mu.RUnlock()
+	sort.Strings(names)
+
+	list := ClusterList{
+		APIVersion: PredictorAPIVersion,
+		Kind:       "ClusterList",
+		Items:      make([]ClusterStatus, len(names)),
+	}
+	// the API servers are checked at once, so that an unreachable cluster does not hold up the others
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			list.Items[i] = ClusterStatus{Name: name, Ready: true}
+			if err := servers[name].checkReady(r.Context()); err != nil {
+				list.Items[i].Ready = false
+				list.Items[i].Reason = err.Error()
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", contentTypeJSON)
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		klog.Error(err)
+	}
+}
@@ -0,0 +1,325 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/client-go/rest"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+	"github.com/clusternet/sample-controller/pkg/predictor/metrics"
+	"github.com/clusternet/sample-controller/pkg/predictor/plugins"
+)
+
+// testKubeconfig returns a kubeconfig of a context per server, using the first one as the current context.
+func testKubeconfig(servers ...string) string {
+	var clusters, contexts string
+	for _, server := range servers {
+		clusters += fmt.Sprintf("- name: %s\n  cluster: {server: \"https://%s.example.com\"}\n", server, server)
+		contexts += fmt.Sprintf("- name: %s\n  context: {cluster: %s, user: admin}\n", server, server)
+	}
+	return "apiVersion: v1\nkind: Config\nclusters:\n" + clusters +
+		"users:\n- name: admin\n  user: {token: secret}\ncontexts:\n" + contexts +
+		"current-context: " + servers[0] + "\n"
+}
+
+func TestLoadKubeconfigs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "kubeconfigs"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		"kubeconfigs/east.yaml":       testKubeconfig("east"),
+		"kubeconfigs/west.kubeconfig": testKubeconfig("west", "unused"),
+		"kubeconfigs/broken.yaml":     "current-context: missing",
+		"kubeconfigs/.hidden":         "not a kubeconfig",
+		"contexts.yaml":               testKubeconfig("north", "south"),
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		source     string
+		wantServer map[string]string
+		wantFailed []string
+		wantErr    bool
+	}{
+		{
+			name:   "directory of kubeconfigs",
+			source: filepath.Join(dir, "kubeconfigs"),
+			wantServer: map[string]string{
+				"east": "https://east.example.com",
+				"west": "https://west.example.com",
+			},
+			wantFailed: []string{"broken"},
+		},
+		{
+			name:   "contexts of a kubeconfig",
+			source: filepath.Join(dir, "contexts.yaml"),
+			wantServer: map[string]string{
+				"north": "https://north.example.com",
+				"south": "https://south.example.com",
+			},
+			wantFailed: []string{},
+		},
+		{
+			name:    "missing",
+			source:  filepath.Join(dir, "missing"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs, failed, err := loadKubeconfigs(tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadKubeconfigs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			servers := make(map[string]string)
+			for name, config := range configs {
+				if len(config.Clusters) != 1 {
+					t.Errorf("loadKubeconfigs() cluster %s has %d clusters, want it minified", name, len(config.Clusters))
+				}
+				for _, cluster := range config.Clusters {
+					servers[name] = cluster.Server
+				}
+			}
+			if !reflect.DeepEqual(servers, tt.wantServer) {
+				t.Errorf("loadKubeconfigs() servers = %v, want %v", servers, tt.wantServer)
+			}
+			if !reflect.DeepEqual(failed.List(), tt.wantFailed) {
+				t.Errorf("loadKubeconfigs() failed = %v, want %v", failed.List(), tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestClusterSet(t *testing.T) {
+	dir := t.TempDir()
+	writeKubeconfig := func(name, data string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".yaml"), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeKubeconfig("east", testKubeconfig("east"))
+	writeKubeconfig("west", testKubeconfig("west"))
+
+	// every cluster has a node of as many cpus as the length of its server address
+	servers := make(map[string]*PredictorServer)
+	set := newClusterSet(dir, func(name string, restConfig *rest.Config) (*PredictorServer, error) {
+		p := newTestServer()
+		p.name = name
+		p.cache.AddNode(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:  *resource.NewQuantity(int64(len(restConfig.Host)), resource.DecimalSI),
+				corev1.ResourcePods: resource.MustParse("110"),
+			}},
+		})
+		var err error
+		p.framework, err = framework.NewFramework(plugins.NewInTreeRegistry(), framework.Plugins{
+			Estimate: []string{plugins.NodeResourcesFitName},
+		}, nil, p)
+		servers[name] = p
+		return p, err
+	})
+	frontend := &PredictorServer{maxRequestBytes: 1 << 20, synced: 1, clusters: set}
+	metrics.Register()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go set.run(stopCh)
+	waitForClusters := func(names ...string) {
+		err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+			set.mu.RLock()
+			defer set.mu.RUnlock()
+			if len(set.clusters) != len(names) {
+				return false, nil
+			}
+			for _, name := range names {
+				if c, ok := set.clusters[name]; !ok || atomic.LoadInt32(&c.server.synced) == 0 {
+					return false, nil
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			t.Fatalf("clusters are not %v", names)
+		}
+	}
+	waitForClusters("east", "west")
+	east := servers["east"]
+
+	body := `{"resources": {"requests": {"cpu": "1"}}}`
+	tests := []struct {
+		name         string
+		path         string
+		cluster      string
+		wantCode     int
+		wantReplicas string
+	}{
+		{
+			name:         "by path",
+			path:         "/clusters/east/accept",
+			wantCode:     http.StatusOK,
+			wantReplicas: "24",
+		},
+		{
+			name:         "by header",
+			path:         "/accept",
+			cluster:      "west",
+			wantCode:     http.StatusOK,
+			wantReplicas: "24",
+		},
+		{
+			name:     "without cluster",
+			path:     "/accept",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown cluster",
+			path:     "/clusters/north/accept",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "metrics are not served by a cluster",
+			path:     "/clusters/east/metrics",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "readiness of a cluster",
+			path:     "/clusters/east/readyz",
+			wantCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(body))
+			if tt.cluster != "" {
+				r.Header.Set(ClusterHeader, tt.cluster)
+			}
+			if tt.path == "/clusters/east/readyz" {
+				// the fake clientset has no API server to check, so that the cluster is marked unsynced
+				atomic.StoreInt32(&east.synced, 0)
+				defer atomic.StoreInt32(&east.synced, 1)
+			}
+			frontend.Handler().ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Fatalf("%s code = %d, want %d, body %s", tt.path, w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantReplicas != "" && w.Body.String() != tt.wantReplicas {
+				t.Errorf("%s replicas = %s, want %s", tt.path, w.Body.String(), tt.wantReplicas)
+			}
+		})
+	}
+
+	// the clusters share the metrics of the predictor, labeled by cluster
+	w := httptest.NewRecorder()
+	frontend.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, cluster := range []string{"east", "west"} {
+		want := fmt.Sprintf(`predictor_http_requests_total{cluster=%q,code="200",endpoint="accept"} 1`, cluster)
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("/metrics does not report %s", want)
+		}
+	}
+
+	// remove a cluster, change another, and add a new one
+	if err := os.Remove(filepath.Join(dir, "west.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	writeKubeconfig("east", testKubeconfig("east", "unused"))
+	writeKubeconfig("north", testKubeconfig("north"))
+	if err := set.reload(); err != nil {
+		t.Fatal(err)
+	}
+	waitForClusters("east", "north")
+	if set.get("east").server != east {
+		t.Errorf("cluster east is restarted, want it kept as its context is unchanged")
+	}
+	writeKubeconfig("east", testKubeconfig("east-2"))
+	if err := set.reload(); err != nil {
+		t.Fatal(err)
+	}
+	waitForClusters("east", "north")
+	if set.get("east").server == east {
+		t.Errorf("cluster east is kept, want it restarted as its context is changed")
+	}
+
+	// the fake clientsets have no API server to check, so that the clusters are marked unsynced
+	atomic.StoreInt32(&set.get("east").server.synced, 0)
+	atomic.StoreInt32(&set.get("north").server.synced, 0)
+	w = httptest.NewRecorder()
+	frontend.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/clusters", nil))
+	var list ClusterList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, item := range list.Items {
+		names = append(names, item.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"east", "north"}) || list.Items[1].Ready || list.Items[1].Reason == "" {
+		t.Errorf("/clusters = %+v, want east and north, which are not ready", list.Items)
+	}
+
+	// the clusters are listed only to the callers allowed to predict against them
+	frontend.authenticator = authenticator.RequestFunc(func(r *http.Request) (*authenticator.Response, bool, error) {
+		if r.Header.Get("Authorization") != "Bearer other-token" {
+			return nil, false, nil
+		}
+		return &authenticator.Response{User: &user.DefaultInfo{Name: "someone"}}, true, nil
+	})
+	frontend.authorizer = authorizer.AuthorizerFunc(func(context.Context, authorizer.Attributes) (authorizer.Decision, string, error) {
+		return authorizer.DecisionNoOpinion, "not the scheduler", nil
+	})
+	w = httptest.NewRecorder()
+	frontend.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/clusters", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("/clusters code = %d, want %d without a token", w.Code, http.StatusUnauthorized)
+	}
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/clusters", nil)
+	r.Header.Set("Authorization", "Bearer other-token")
+	frontend.Handler().ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("/clusters code = %d, want %d for a caller not allowed", w.Code, http.StatusForbidden)
+	}
+}
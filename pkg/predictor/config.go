@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+	"github.com/clusternet/sample-controller/pkg/predictor/plugins"
+)
+
+// PredictorConfiguration configures the plugins of the predictor.
+type PredictorConfiguration struct {
+	// Plugins enables the plugins of each extension point in order.
+	// An extension point that is not set keeps its default plugins.
+	Plugins framework.Plugins `json:"plugins,omitempty"`
+	// PluginConfig is the list of arguments of the plugins.
+	PluginConfig []framework.PluginConfig `json:"pluginConfig,omitempty"`
+}
+
+// defaultClusterResourceCaps bounds the replicas by the available IPs reported on the nodes of a TKE
+// cluster. It does not constrain anything on clusters without such labels.
+var defaultClusterResourceCaps = plugins.ClusterResourceCapsArgs{
+	Caps: []plugins.ResourceCap{
+		{
+			Name:   "tke-available-ip",
+			Source: plugins.CapSourceLabel,
+			Key:    "tke.cloud.tencent.com/available-ip-count",
+			Scope:  plugins.CapScopeNode,
+		},
+	},
+}
+
+// NewDefaultConfiguration returns the configuration used when no configuration file is given.
+func NewDefaultConfiguration() *PredictorConfiguration {
+	capsArgs, _ := json.Marshal(defaultClusterResourceCaps)
+	return &PredictorConfiguration{
+		Plugins: framework.Plugins{
+			Filter: []string{plugins.NodeHealthName, plugins.TaintTolerationName, plugins.NodeAffinityName,
+				plugins.InterPodAffinityName, plugins.PodTopologySpreadName, TKECloudHSSDName},
+			Estimate: []string{plugins.NodeResourcesFitName, plugins.InterPodAffinityName},
+			Cap: []string{plugins.ClusterResourceCapsName, plugins.NamespaceResourceQuotaName,
+				plugins.InterPodAffinityName, plugins.PodTopologySpreadName},
+		},
+		PluginConfig: []framework.PluginConfig{
+			{
+				Name: plugins.ClusterResourceCapsName,
+				Args: capsArgs,
+			},
+		},
+	}
+}
+
+// LoadConfiguration loads the configuration from a yaml or json file on top of the default configuration.
+func LoadConfiguration(path string) (*PredictorConfiguration, error) {
+	config := NewDefaultConfiguration()
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error of read predictor configuration : %v", err)
+	}
+	var loaded PredictorConfiguration
+	if err = yaml.UnmarshalStrict(data, &loaded); err != nil {
+		return nil, fmt.Errorf("error of decode predictor configuration %s : %v", path, err)
+	}
+
+	if loaded.Plugins.Filter != nil {
+		config.Plugins.Filter = loaded.Plugins.Filter
+	}
+	if loaded.Plugins.Estimate != nil {
+		config.Plugins.Estimate = loaded.Plugins.Estimate
+	}
+	if loaded.Plugins.Cap != nil {
+		config.Plugins.Cap = loaded.Plugins.Cap
+	}
+	// args in the file replace the default args of the same plugin
+	for _, pc := range loaded.PluginConfig {
+		replaced := false
+		for i := range config.PluginConfig {
+			if config.PluginConfig[i].Name == pc.Name {
+				config.PluginConfig[i] = pc
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			config.PluginConfig = append(config.PluginConfig, pc)
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// StateKey is the key of the data stored in CycleState.
+type StateKey string
+
+// StateData is the data stored in CycleState.
+type StateData interface{}
+
+// CycleState provides a mechanism for plugins to store and retrieve arbitrary data during a prediction.
+// Data stored by one plugin can be read, altered, or deleted by another plugin.
+type CycleState struct {
+	mx      sync.RWMutex
+	storage map[StateKey]StateData
+
+	// SkipPlugins are the plugins whose PreFilter returned Skip. They are not run at Filter and Estimate.
+	SkipPlugins sets.String
+}
+
+// NewCycleState initializes a new CycleState and returns its pointer.
+func NewCycleState() *CycleState {
+	return &CycleState{
+		storage:     make(map[StateKey]StateData),
+		SkipPlugins: sets.NewString(),
+	}
+}
+
+// Read retrieves data with the given key from CycleState. If the key is not present an error is returned.
+func (c *CycleState) Read(key StateKey) (StateData, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	if v, ok := c.storage[key]; ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("%q not found in cycle state", key)
+}
+
+// Write stores the given key-value pair in CycleState.
+func (c *CycleState) Write(key StateKey, val StateData) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.storage[key] = val
+}
+
+// Delete deletes data with the given key from CycleState.
+func (c *CycleState) Delete(key StateKey) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	delete(c.storage, key)
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+// TopologyDomains are the pods of every topology domain of a node label key, i.e. of the nodes
+// sharing a value of the label.
+type TopologyDomains struct {
+	// Nodes is the count of nodes with the label.
+	Nodes int
+	// PodGroups are the pod groups of the nodes of every domain, keyed by the value of the label and
+	// then by PodGroupKey. Every domain is present, with a nil map when it has no pods.
+	// The maps must not be changed, as they are shared with other snapshots and NodeInfos.
+	PodGroups map[string]map[string]PodGroup
+}
+
+// TopologyDomains returns the pods of every topology domain of the label key. They are counted on first
+// use, and then carried over by the snapshots updated from this one, so that the pods of a large cluster
+// are not walked by every prediction.
+func (s *Snapshot) TopologyDomains(key string) *TopologyDomains {
+	s.domainsMu.Lock()
+	domains, ok := s.domains[key]
+	s.domainsMu.Unlock()
+	if ok {
+		return domains
+	}
+
+	// concurrent predictions may count the same key, and only the first one is kept
+	domains = s.countTopologyDomains(key)
+	s.domainsMu.Lock()
+	defer s.domainsMu.Unlock()
+	if counted, ok := s.domains[key]; ok {
+		return counted
+	}
+	if s.domains == nil {
+		s.domains = make(map[string]*TopologyDomains)
+	}
+	s.domains[key] = domains
+	return domains
+}
+
+// countTopologyDomains counts the pods of every topology domain of the label key.
+func (s *Snapshot) countTopologyDomains(key string) *TopologyDomains {
+	values := s.index()[key]
+	domains := &TopologyDomains{PodGroups: make(map[string]map[string]PodGroup, len(values))}
+	for value, names := range values {
+		var groups map[string]PodGroup
+		owned := false
+		for _, name := range names {
+			nodeInfo := s.Get(name)
+			if nodeInfo == nil {
+				continue
+			}
+			domains.Nodes++
+			groups, owned = addPodGroups(groups, owned, nodeInfo.PodGroups(), 1)
+		}
+		domains.PodGroups[value] = groups
+	}
+	return domains
+}
+
+// updateTopologyDomains returns the topology domains of the label key counted by the previous snapshot,
+// with the pods of the changed nodes moved from their domains in the previous snapshot to those in s.
+func (s *Snapshot) updateTopologyDomains(key string, previous *Snapshot, domains *TopologyDomains, changed []string) *TopologyDomains {
+	updated := &TopologyDomains{
+		Nodes:     domains.Nodes,
+		PodGroups: make(map[string]map[string]PodGroup, len(domains.PodGroups)),
+	}
+	for value, groups := range domains.PodGroups {
+		updated.PodGroups[value] = groups
+	}
+
+	// owned are the domains whose groups are copied by this update, and touched are all the changed ones
+	owned := make(map[string]bool)
+	touched := make(map[string]bool)
+	move := func(nodeInfo *NodeInfo, sign int64) {
+		if nodeInfo == nil {
+			return
+		}
+		value, ok := nodeInfo.Node().Labels[key]
+		if !ok {
+			return
+		}
+		updated.Nodes += int(sign)
+		updated.PodGroups[value], owned[value] = addPodGroups(updated.PodGroups[value], owned[value], nodeInfo.PodGroups(), sign)
+		touched[value] = true
+	}
+	for _, name := range changed {
+		move(previous.Get(name), -1)
+		move(s.Get(name), 1)
+	}
+
+	values := s.index()[key]
+	for value := range touched {
+		if len(values[value]) == 0 {
+			delete(updated.PodGroups, value)
+		} else if len(updated.PodGroups[value]) == 0 {
+			updated.PodGroups[value] = nil
+		}
+	}
+	return updated
+}
+
+// addPodGroups adds the pod groups of a node to those of a domain, or subtracts them when sign is -1.
+// The groups of the domain are copied before they are changed unless owned, i.e. copied by the caller,
+// while the groups of the node are shared by a domain without other pods.
+func addPodGroups(groups map[string]PodGroup, owned bool, nodeGroups map[string]PodGroup, sign int64) (map[string]PodGroup, bool) {
+	if len(nodeGroups) == 0 {
+		return groups, owned
+	}
+	if len(groups) == 0 && sign > 0 {
+		return nodeGroups, false
+	}
+	if !owned {
+		copied := make(map[string]PodGroup, len(groups)+len(nodeGroups))
+		for key, group := range groups {
+			copied[key] = group
+		}
+		groups, owned = copied, true
+	}
+	for key, nodeGroup := range nodeGroups {
+		group, ok := groups[key]
+		if !ok {
+			group = PodGroup{Namespace: nodeGroup.Namespace, Labels: nodeGroup.Labels}
+		}
+		group.Count += sign * nodeGroup.Count
+		if group.Count <= 0 {
+			delete(groups, key)
+			continue
+		}
+		groups[key] = group
+	}
+	return groups, owned
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// Framework runs the enabled plugins to predict how many replicas a cluster could accept.
+type Framework struct {
+	preFilterPlugins []PreFilterPlugin
+	filterPlugins    []FilterPlugin
+	estimatePlugins  []EstimatePlugin
+	capPlugins       []CapPlugin
+}
+
+// Result is the result of a prediction.
+type Result struct {
+	// Replicas is the max replicas the cluster could accept.
+	Replicas int64
+	// NodeReplicas is the estimated replicas of every node that passed all filter plugins.
+	NodeReplicas map[string]int64
+	// LimitedBy is the resource that bounds the replicas of every node that passed all filter plugins.
+	LimitedBy map[string]string
+	// Rejections is the status of every node that was filtered out.
+	Rejections map[string]*Status
+	// Cap is the cluster-level cap that bounded Replicas, if any.
+	Cap *CapResult
+	// Preemption is the result when the pods of lower priority than the replicas are preempted, which is
+	// nil unless the requirements could preempt. The result above is then guaranteed without preemption.
+	Preemption *Result
+}
+
+// CapResult is a cluster-level cap applied to a prediction.
+type CapResult struct {
+	Plugin   string
+	Replicas int64
+	Reason   string
+}
+
+// NewFramework builds the plugins enabled in plugins with the factories in registry.
+func NewFramework(registry Registry, plugins Plugins, pluginConfig []PluginConfig, handle Handle) (*Framework, error) {
+	args := make(map[string]PluginConfig, len(pluginConfig))
+	for _, pc := range pluginConfig {
+		if _, ok := args[pc.Name]; ok {
+			return nil, fmt.Errorf("repeated config for plugin %s", pc.Name)
+		}
+		args[pc.Name] = pc
+	}
+
+	// a plugin enabled at several extension points is only built once
+	built := make(map[string]Plugin)
+	getPlugin := func(name string) (Plugin, error) {
+		if pl, ok := built[name]; ok {
+			return pl, nil
+		}
+		factory, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("plugin %q does not exist", name)
+		}
+		pl, err := factory(args[name].Args, handle)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing plugin %q: %v", name, err)
+		}
+		built[name] = pl
+		return pl, nil
+	}
+
+	f := &Framework{}
+	for _, name := range plugins.Filter {
+		pl, err := getPlugin(name)
+		if err != nil {
+			return nil, err
+		}
+		filterPlugin, ok := pl.(FilterPlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %q does not extend filter plugin", name)
+		}
+		f.filterPlugins = append(f.filterPlugins, filterPlugin)
+	}
+	for _, name := range plugins.Estimate {
+		pl, err := getPlugin(name)
+		if err != nil {
+			return nil, err
+		}
+		estimatePlugin, ok := pl.(EstimatePlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %q does not extend estimate plugin", name)
+		}
+		f.estimatePlugins = append(f.estimatePlugins, estimatePlugin)
+	}
+	for _, name := range plugins.Cap {
+		pl, err := getPlugin(name)
+		if err != nil {
+			return nil, err
+		}
+		capPlugin, ok := pl.(CapPlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %q does not extend cap plugin", name)
+		}
+		f.capPlugins = append(f.capPlugins, capPlugin)
+	}
+
+	// every enabled plugin implementing PreFilterPlugin is run before filtering
+	for _, name := range sets.StringKeySet(built).List() {
+		if preFilterPlugin, ok := built[name].(PreFilterPlugin); ok {
+			f.preFilterPlugins = append(f.preFilterPlugins, preFilterPlugin)
+		}
+	}
+
+	if len(f.estimatePlugins) == 0 {
+		return nil, fmt.Errorf("at least one estimate plugin is required")
+	}
+	return f, nil
+}
+
+// Predict runs all the plugins against the nodes of the snapshot. When the requirements could preempt,
+// they are run again against the nodes without the pods of lower priority.
+func (f *Framework) Predict(ctx context.Context, requirements *Requirements, snapshot *Snapshot) (*Result, error) {
+	result, err := f.predict(ctx, requirements, snapshot)
+	if err != nil || !requirements.CanPreempt() {
+		return result, err
+	}
+
+	preempted, ok := snapshot.WithoutPodsBelow(*requirements.Priority)
+	if !ok {
+		// nothing could be preempted
+		preemption := *result
+		result.Preemption = &preemption
+		return result, nil
+	}
+	result.Preemption, err = f.predict(ctx, requirements, preempted)
+	if err != nil {
+		return nil, fmt.Errorf("error of predict with preemption : %v", err)
+	}
+	return result, nil
+}
+
+// predict runs the plugins against the snapshot.
+func (f *Framework) predict(ctx context.Context, requirements *Requirements, snapshot *Snapshot) (*Result, error) {
+	result := &Result{
+		NodeReplicas: make(map[string]int64, snapshot.NumNodes()),
+		LimitedBy:    make(map[string]string, snapshot.NumNodes()),
+		Rejections:   make(map[string]*Status),
+	}
+
+	state := NewCycleState()
+	if status := f.RunPreFilterPlugins(ctx, state, requirements, snapshot); !status.IsSuccess() {
+		return nil, fmt.Errorf("prefilter plugin %q failed: %v", status.Plugin(), status.AsError())
+	}
+
+	nodeInfos := snapshot.List()
+	feasibleNodes := make([]*NodeInfo, 0, len(nodeInfos))
+	for _, nodeInfo := range nodeInfos {
+		status := f.RunFilterPlugins(ctx, state, requirements, nodeInfo)
+		if status.Code() == Error {
+			return nil, status.AsError()
+		}
+		if !status.IsSuccess() {
+			klog.V(5).Infof("node %s is filtered out by %s: %s", nodeInfo.Node().Name, status.Plugin(), status.Message())
+			result.Rejections[nodeInfo.Node().Name] = status
+			continue
+		}
+
+		estimate, status := f.RunEstimatePlugins(ctx, state, requirements, nodeInfo)
+		if status.Code() == Error {
+			return nil, fmt.Errorf("estimate plugin %q failed: %v", status.Plugin(), status.AsError())
+		}
+		if !status.IsSuccess() {
+			klog.V(5).Infof("node %s is estimated to hold no replica by %s: %s", nodeInfo.Node().Name, status.Plugin(), status.Message())
+			result.Rejections[nodeInfo.Node().Name] = status
+			continue
+		}
+		feasibleNodes = append(feasibleNodes, nodeInfo)
+		result.NodeReplicas[nodeInfo.Node().Name] = estimate.Replicas
+		result.LimitedBy[nodeInfo.Node().Name] = estimate.LimitedBy
+		if result.Replicas > math.MaxInt64-estimate.Replicas {
+			result.Replicas = math.MaxInt64
+		} else {
+			result.Replicas += estimate.Replicas
+		}
+	}
+
+	for _, pl := range f.capPlugins {
+		limit, status := pl.Cap(ctx, state, requirements, feasibleNodes, result.NodeReplicas)
+		if status.IsSkip() {
+			continue
+		}
+		if !status.IsSuccess() {
+			return nil, fmt.Errorf("cap plugin %q failed: %v", pl.Name(), status.AsError())
+		}
+		klog.V(5).Infof("cluster is capped to %d replicas by %s", limit, pl.Name())
+		if limit < result.Replicas {
+			result.Replicas = limit
+			result.Cap = &CapResult{
+				Plugin:   pl.Name(),
+				Replicas: limit,
+				Reason:   status.Message(),
+			}
+		}
+	}
+	return result, nil
+}
+
+// RunPreFilterPlugins runs the prefilter plugins, and stops at the first plugin returning neither Success nor Skip.
+// The plugins returning Skip are recorded in the SkipPlugins of the state.
+func (f *Framework) RunPreFilterPlugins(ctx context.Context, state *CycleState, requirements *Requirements, snapshot *Snapshot) *Status {
+	for _, pl := range f.preFilterPlugins {
+		status := pl.PreFilter(ctx, state, requirements, snapshot)
+		if status.IsSkip() {
+			state.SkipPlugins.Insert(pl.Name())
+			continue
+		}
+		if !status.IsSuccess() {
+			return status.WithPlugin(pl.Name())
+		}
+	}
+	return nil
+}
+
+// RunFilterPlugins runs the filter plugins in order, and stops at the first plugin returning neither Success nor Skip.
+func (f *Framework) RunFilterPlugins(ctx context.Context, state *CycleState, requirements *Requirements, nodeInfo *NodeInfo) *Status {
+	for _, pl := range f.filterPlugins {
+		if state.SkipPlugins.Has(pl.Name()) {
+			continue
+		}
+		status := pl.Filter(ctx, state, requirements, nodeInfo)
+		if !status.IsSuccess() && !status.IsSkip() {
+			return status.WithPlugin(pl.Name())
+		}
+	}
+	return nil
+}
+
+// RunEstimatePlugins returns the minimum estimate of all the estimate plugins not returning Skip, and stops
+// at the first plugin returning neither Success nor Skip. A node estimated to hold no replica is Unschedulable
+// by the plugin estimating so, the same as the nodes filtered out.
+func (f *Framework) RunEstimatePlugins(ctx context.Context, state *CycleState, requirements *Requirements, nodeInfo *NodeInfo) (NodeEstimate, *Status) {
+	result := NodeEstimate{Replicas: math.MaxInt64}
+	for _, pl := range f.estimatePlugins {
+		if state.SkipPlugins.Has(pl.Name()) {
+			continue
+		}
+		estimate, status := pl.Estimate(ctx, state, requirements, nodeInfo)
+		if status.IsSkip() {
+			continue
+		}
+		if !status.IsSuccess() {
+			return NodeEstimate{}, status.WithPlugin(pl.Name())
+		}
+		if estimate.Replicas <= 0 {
+			reason := "node could hold no replica"
+			if estimate.LimitedBy != "" {
+				reason = fmt.Sprintf("node could hold no replica, limited by %s", estimate.LimitedBy)
+			}
+			return NodeEstimate{}, NewStatus(Unschedulable, reason).WithPlugin(pl.Name())
+		}
+		if estimate.Replicas < result.Replicas {
+			result = estimate
+			if result.LimitedBy == "" {
+				result.LimitedBy = pl.Name()
+			}
+		}
+	}
+	return result, nil
+}
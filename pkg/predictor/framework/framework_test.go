@@ -0,0 +1,285 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakePlugin reads its behavior from the labels of the node, e.g. "<name>/filter": "reject", or
+// "<name>/estimate" of a number, "skip" or "reject".
+// A cap of -1 skips, a cap of -2 caps to the count of nodes seen at PreFilter, and a cap of -3 skips at PreFilter.
+type fakePlugin struct {
+	name string
+	cap  int64
+}
+
+func (pl *fakePlugin) Name() string {
+	return pl.name
+}
+
+func (pl *fakePlugin) PreFilter(_ context.Context, state *CycleState, _ *Requirements, snapshot *Snapshot) *Status {
+	if pl.cap == -3 {
+		return NewStatus(Skip)
+	}
+	state.Write(StateKey(pl.name), int64(snapshot.NumNodes()))
+	return nil
+}
+
+func (pl *fakePlugin) Filter(_ context.Context, _ *CycleState, _ *Requirements, nodeInfo *NodeInfo) *Status {
+	if nodeInfo.Node().Labels[pl.name+"/filter"] == "reject" {
+		return NewStatus(Unschedulable, "rejected by "+pl.name)
+	}
+	return nil
+}
+
+func (pl *fakePlugin) Estimate(_ context.Context, _ *CycleState, _ *Requirements, nodeInfo *NodeInfo) (NodeEstimate, *Status) {
+	switch nodeInfo.Node().Labels[pl.name+"/estimate"] {
+	case "skip":
+		return NodeEstimate{}, NewStatus(Skip)
+	case "reject":
+		return NodeEstimate{}, NewStatus(Unschedulable, "rejected by "+pl.name)
+	}
+	replicas, err := strconv.ParseInt(nodeInfo.Node().Labels[pl.name+"/estimate"], 10, 64)
+	if err != nil {
+		return NodeEstimate{}, AsStatus(err)
+	}
+	return NodeEstimate{Replicas: replicas}, nil
+}
+
+func (pl *fakePlugin) Cap(_ context.Context, state *CycleState, _ *Requirements, _ []*NodeInfo, _ map[string]int64) (int64, *Status) {
+	if pl.cap == -2 {
+		count, err := state.Read(StateKey(pl.name))
+		if err != nil {
+			return 0, AsStatus(err)
+		}
+		return count.(int64), nil
+	}
+	if pl.cap < 0 {
+		return 0, NewStatus(Skip)
+	}
+	return pl.cap, nil
+}
+
+func newFakeRegistry(caps map[string]int64) Registry {
+	registry := Registry{}
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		registry[name] = func(_ json.RawMessage, _ Handle) (Plugin, error) {
+			return &fakePlugin{name: name, cap: caps[name]}, nil
+		}
+	}
+	return registry
+}
+
+func TestPredict(t *testing.T) {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"a/estimate": "4", "b/estimate": "3"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{"a/estimate": "2", "b/estimate": "5"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-3", Labels: map[string]string{"a/estimate": "9", "b/estimate": "9", "b/filter": "reject"}}},
+	}
+	nodeInfos := make([]*NodeInfo, 0, len(nodes))
+	for _, node := range nodes {
+		nodeInfos = append(nodeInfos, NewNodeInfo(node))
+	}
+
+	tests := []struct {
+		name    string
+		plugins Plugins
+		caps    map[string]int64
+
+		wantReplicas     int64
+		wantNodeReplicas map[string]int64
+		wantLimitedBy    map[string]string
+		wantCap          *CapResult
+	}{
+		{
+			name:             "minimum of estimate plugins",
+			plugins:          Plugins{Estimate: []string{"a", "b"}},
+			wantReplicas:     14,
+			wantNodeReplicas: map[string]int64{"node-1": 3, "node-2": 2, "node-3": 9},
+			wantLimitedBy:    map[string]string{"node-1": "b", "node-2": "a", "node-3": "a"},
+		},
+		{
+			name:             "filtered nodes are excluded",
+			plugins:          Plugins{Filter: []string{"a", "b"}, Estimate: []string{"a"}},
+			wantReplicas:     6,
+			wantNodeReplicas: map[string]int64{"node-1": 4, "node-2": 2},
+			wantLimitedBy:    map[string]string{"node-1": "a", "node-2": "a"},
+		},
+		{
+			name:             "smallest cap applies",
+			plugins:          Plugins{Filter: []string{"b"}, Estimate: []string{"a"}, Cap: []string{"a", "b", "c"}},
+			caps:             map[string]int64{"a": 5, "b": -1, "c": 4},
+			wantReplicas:     4,
+			wantNodeReplicas: map[string]int64{"node-1": 4, "node-2": 2},
+			wantLimitedBy:    map[string]string{"node-1": "a", "node-2": "a"},
+			wantCap:          &CapResult{Plugin: "c", Replicas: 4},
+		},
+		{
+			name:             "skipped caps do not constrain",
+			plugins:          Plugins{Filter: []string{"b"}, Estimate: []string{"a"}, Cap: []string{"b"}},
+			caps:             map[string]int64{"b": -1},
+			wantReplicas:     6,
+			wantNodeReplicas: map[string]int64{"node-1": 4, "node-2": 2},
+			wantLimitedBy:    map[string]string{"node-1": "a", "node-2": "a"},
+		},
+		{
+			name:             "prefilter sees all nodes",
+			plugins:          Plugins{Filter: []string{"b"}, Estimate: []string{"a"}, Cap: []string{"c"}},
+			caps:             map[string]int64{"c": -2},
+			wantReplicas:     3,
+			wantNodeReplicas: map[string]int64{"node-1": 4, "node-2": 2},
+			wantLimitedBy:    map[string]string{"node-1": "a", "node-2": "a"},
+			wantCap:          &CapResult{Plugin: "c", Replicas: 3},
+		},
+		{
+			name:             "skipped at prefilter",
+			plugins:          Plugins{Filter: []string{"b"}, Estimate: []string{"a", "b"}},
+			caps:             map[string]int64{"b": -3},
+			wantReplicas:     15,
+			wantNodeReplicas: map[string]int64{"node-1": 4, "node-2": 2, "node-3": 9},
+			wantLimitedBy:    map[string]string{"node-1": "a", "node-2": "a", "node-3": "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFramework(newFakeRegistry(tt.caps), tt.plugins, nil, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			result, err := f.Predict(context.TODO(), &Requirements{}, NewSnapshot(nodeInfos, nil))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.Replicas != tt.wantReplicas {
+				t.Errorf("Predict() replicas = %d, want %d", result.Replicas, tt.wantReplicas)
+			}
+			if !reflect.DeepEqual(result.NodeReplicas, tt.wantNodeReplicas) {
+				t.Errorf("Predict() node replicas = %v, want %v", result.NodeReplicas, tt.wantNodeReplicas)
+			}
+			if !reflect.DeepEqual(result.LimitedBy, tt.wantLimitedBy) {
+				t.Errorf("Predict() limited by = %v, want %v", result.LimitedBy, tt.wantLimitedBy)
+			}
+			if !reflect.DeepEqual(result.Cap, tt.wantCap) {
+				t.Errorf("Predict() cap = %v, want %v", result.Cap, tt.wantCap)
+			}
+			for name := range result.Rejections {
+				if _, ok := tt.wantNodeReplicas[name]; ok {
+					t.Errorf("Predict() node %s is both estimated and rejected", name)
+				}
+			}
+		})
+	}
+}
+
+func TestPredictEstimateStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+
+		wantReplicas  int64
+		wantRejection string
+		wantErr       bool
+	}{
+		{
+			name:         "skipped estimates do not bound",
+			labels:       map[string]string{"a/estimate": "skip", "b/estimate": "3"},
+			wantReplicas: 3,
+		},
+		{
+			name:          "unschedulable excludes the node",
+			labels:        map[string]string{"a/estimate": "4", "b/estimate": "reject"},
+			wantRejection: "b",
+		},
+		{
+			name:          "no replica excludes the node",
+			labels:        map[string]string{"a/estimate": "4", "b/estimate": "0"},
+			wantRejection: "b",
+		},
+		{
+			name:    "error fails the prediction",
+			labels:  map[string]string{"a/estimate": "4", "b/estimate": "broken"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFramework(newFakeRegistry(nil), Plugins{Estimate: []string{"a", "b"}}, nil, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			nodeInfos := []*NodeInfo{
+				NewNodeInfo(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: tt.labels}}),
+			}
+			result, err := f.Predict(context.TODO(), &Requirements{}, NewSnapshot(nodeInfos, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Predict() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if result.Replicas != tt.wantReplicas {
+				t.Errorf("Predict() replicas = %d, want %d", result.Replicas, tt.wantReplicas)
+			}
+			if got := result.Rejections["node-1"].Plugin(); got != tt.wantRejection {
+				t.Errorf("Predict() node rejected by %q, want %q", got, tt.wantRejection)
+			}
+			if _, feasible := result.NodeReplicas["node-1"]; feasible != (tt.wantRejection == "") {
+				t.Errorf("Predict() node feasible = %v, want rejection %q", feasible, tt.wantRejection)
+			}
+		})
+	}
+}
+
+func TestNewFramework(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugins Plugins
+		wantErr bool
+	}{
+		{
+			name:    "unknown plugin",
+			plugins: Plugins{Estimate: []string{"a", "d"}},
+			wantErr: true,
+		},
+		{
+			name:    "no estimate plugin",
+			plugins: Plugins{Filter: []string{"a"}},
+			wantErr: true,
+		},
+		{
+			name:    "plugin enabled at several extension points",
+			plugins: Plugins{Filter: []string{"a"}, Estimate: []string{"a"}, Cap: []string{"a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFramework(newFakeRegistry(nil), tt.plugins, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFramework() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
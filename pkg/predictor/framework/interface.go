@@ -0,0 +1,227 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Code is the status code returned by a plugin.
+type Code int
+
+const (
+	// Success means the plugin ran correctly and found the node feasible.
+	Success Code = iota
+	// Error is used for internal plugin errors, unexpected input, etc.
+	Error
+	// Unschedulable means the node can not hold any replica of the workload.
+	Unschedulable
+	// Skip means the plugin has nothing to say about the current request.
+	Skip
+)
+
+var codes = []string{"Success", "Error", "Unschedulable", "Skip"}
+
+func (c Code) String() string {
+	if int(c) < len(codes) {
+		return codes[c]
+	}
+	return fmt.Sprintf("Code(%d)", int(c))
+}
+
+// Status indicates the result of running a plugin. A nil status is a success.
+type Status struct {
+	code    Code
+	reasons []string
+	err     error
+	plugin  string
+}
+
+// NewStatus makes a Status out of the given code and reasons.
+func NewStatus(code Code, reasons ...string) *Status {
+	s := &Status{
+		code:    code,
+		reasons: reasons,
+	}
+	if code == Error {
+		s.err = errors.New(s.Message())
+	}
+	return s
+}
+
+// AsStatus wraps an error in a Status.
+func AsStatus(err error) *Status {
+	if err == nil {
+		return nil
+	}
+	return &Status{
+		code:    Error,
+		reasons: []string{err.Error()},
+		err:     err,
+	}
+}
+
+// Code returns the code of the status.
+func (s *Status) Code() Code {
+	if s == nil {
+		return Success
+	}
+	return s.code
+}
+
+// Reasons returns the reasons of the status.
+func (s *Status) Reasons() []string {
+	if s == nil {
+		return nil
+	}
+	return s.reasons
+}
+
+// Message returns a concatenated message of the reasons of the status.
+func (s *Status) Message() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(s.reasons, ", ")
+}
+
+// Plugin returns the name of the plugin that returned the status.
+func (s *Status) Plugin() string {
+	if s == nil {
+		return ""
+	}
+	return s.plugin
+}
+
+// WithPlugin sets the name of the plugin that returned the status.
+func (s *Status) WithPlugin(plugin string) *Status {
+	s.plugin = plugin
+	return s
+}
+
+// IsSuccess returns true if and only if the status is nil or its code is Success.
+func (s *Status) IsSuccess() bool {
+	return s.Code() == Success
+}
+
+// IsSkip returns true if the status code is Skip.
+func (s *Status) IsSkip() bool {
+	return s.Code() == Skip
+}
+
+// AsError returns nil if the status is a success, otherwise returns an error with the reasons.
+func (s *Status) AsError() error {
+	if s.IsSuccess() || s.IsSkip() {
+		return nil
+	}
+	if s.err != nil {
+		return s.err
+	}
+	return errors.New(s.Message())
+}
+
+// Plugin is the parent type for all the predictor plugins.
+type Plugin interface {
+	Name() string
+}
+
+// PreFilterPlugin is an interface for plugins that compute cluster-wide state before filtering,
+// e.g. the existing pods in every topology domain. The state is written to the CycleState and read
+// back by the other extension points of the same plugin.
+type PreFilterPlugin interface {
+	Plugin
+	// PreFilter is called once per prediction with the snapshot of all the nodes. A status other than
+	// Success or Skip fails the prediction. A plugin returning Skip is not run at Filter and Estimate
+	// of the same prediction, as it has nothing to check.
+	PreFilter(ctx context.Context, state *CycleState, requirements *Requirements, snapshot *Snapshot) *Status
+}
+
+// FilterPlugin is an interface for filter plugins. These plugins are called to decide whether a node
+// could hold any replica of the workload. A node is excluded as soon as one filter plugin returns
+// an Unschedulable status, while an Error status fails the prediction.
+type FilterPlugin interface {
+	Plugin
+	// Filter is called by the framework for every node.
+	Filter(ctx context.Context, state *CycleState, requirements *Requirements, nodeInfo *NodeInfo) *Status
+}
+
+// NodeEstimate is the max replicas a node could hold, as estimated by an estimate plugin.
+type NodeEstimate struct {
+	Replicas int64
+	// LimitedBy is the resource that bounds the replicas, e.g. "cpu".
+	// It defaults to the name of the plugin when left empty.
+	LimitedBy string
+}
+
+// EstimatePlugin is an interface for estimate plugins. These plugins are called for every node that
+// passed all filter plugins, and the replicas of the node is the minimum of all estimate plugins.
+type EstimatePlugin interface {
+	Plugin
+	// Estimate returns the max replicas of the workload the node could hold. A plugin returns a Skip
+	// status when it does not bound the node, and an Unschedulable status to exclude the node the same
+	// as a filter plugin, while an Error status fails the prediction.
+	Estimate(ctx context.Context, state *CycleState, requirements *Requirements, nodeInfo *NodeInfo) (NodeEstimate, *Status)
+}
+
+// CapPlugin is an interface for cluster-level plugins. These plugins bound the total replicas of
+// the whole cluster, e.g. by the count of available IPs in the cluster.
+type CapPlugin interface {
+	Plugin
+	// Cap returns the max replicas the cluster could accept. nodeInfos are the nodes that passed all
+	// filter plugins, and estimates are their replicas keyed by node name.
+	// A plugin returns a Skip status when it should not constrain the result, and may explain
+	// the cap with the reasons of a Success status.
+	Cap(ctx context.Context, state *CycleState, requirements *Requirements, nodeInfos []*NodeInfo, estimates map[string]int64) (int64, *Status)
+}
+
+// Handle provides plugins with access to the cluster.
+type Handle interface {
+	// ClientSet returns a kubernetes clientSet.
+	ClientSet() kubernetes.Interface
+	// SharedInformerFactory returns the shared informer factory of the predictor.
+	// Informers must be requested when the plugin is built, so that they are started with the factory.
+	SharedInformerFactory() informers.SharedInformerFactory
+}
+
+// PluginFactory is a function that builds a plugin.
+type PluginFactory func(args json.RawMessage, handle Handle) (Plugin, error)
+
+// Registry is a collection of all available plugins.
+type Registry map[string]PluginFactory
+
+// Register adds a new plugin to the registry. If a plugin with the same name exists, it returns an error.
+func (r Registry) Register(name string, factory PluginFactory) error {
+	if _, ok := r[name]; ok {
+		return fmt.Errorf("a plugin named %v already exists", name)
+	}
+	r[name] = factory
+	return nil
+}
+
+// Merge merges the provided registry to the current one.
+func (r Registry) Merge(in Registry) error {
+	for name, factory := range in {
+		if err := r.Register(name, factory); err != nil {
+			return err
+		}
+	}
+	return nil
+}
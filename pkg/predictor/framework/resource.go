@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"math"
+
+	"gopkg.in/inf.v0"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// IsPodTerminal checks whether the pod has finished and no longer holds node resources.
+func IsPodTerminal(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// ComputePodResourceRequest returns the resources the scheduler reserves for the pod, which is
+// the larger of the sum of all containers and the largest init container, plus the pod overhead.
+func ComputePodResourceRequest(pod *corev1.Pod) corev1.ResourceList {
+	result := corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		AddResourceList(result, container.Resources.Requests)
+	}
+	// init containers run one by one before the regular containers start
+	for _, container := range pod.Spec.InitContainers {
+		MaxResourceList(result, container.Resources.Requests)
+	}
+	if pod.Spec.Overhead != nil {
+		AddResourceList(result, pod.Spec.Overhead)
+	}
+	return result
+}
+
+// AddResourceList adds the resources in newList to list.
+func AddResourceList(list, newList corev1.ResourceList) {
+	for name, quantity := range newList {
+		if value, ok := list[name]; !ok {
+			list[name] = quantity.DeepCopy()
+		} else {
+			value.Add(quantity)
+			list[name] = value
+		}
+	}
+}
+
+// MaxResourceList sets list to the greater of list/newList for every resource in newList.
+func MaxResourceList(list, newList corev1.ResourceList) {
+	for name, quantity := range newList {
+		if value, ok := list[name]; !ok || quantity.Cmp(value) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// MultiplyQuantity returns value multiplied by n, e.g. the resources requested by n replicas.
+func MultiplyQuantity(value resource.Quantity, n int64) resource.Quantity {
+	product := new(inf.Dec).Mul(value.AsDec(), inf.NewDec(n, 0))
+	return *resource.NewDecimalQuantity(*product, value.Format)
+}
+
+// maxMilliQuantity is the largest quantity whose milli value fits in int64.
+var maxMilliQuantity = *resource.NewQuantity(math.MaxInt64/1000, resource.DecimalSI)
+
+// DivideQuantity returns how many times divisor fits in value, i.e. floor(value / divisor).
+// It is computed in milli units, so that e.g. a 100m cpu request fits 10 times in a core,
+// and falls back to exact decimals when the milli value of value would overflow int64.
+func DivideQuantity(value, divisor resource.Quantity) int64 {
+	if value.Sign() <= 0 || divisor.Sign() <= 0 || divisor.Cmp(value) > 0 {
+		return 0
+	}
+	if value.Cmp(maxMilliQuantity) < 0 {
+		return value.MilliValue() / divisor.MilliValue()
+	}
+
+	quotient := new(inf.Dec).QuoRound(value.AsDec(), divisor.AsDec(), 0, inf.RoundDown)
+	if replicas, ok := quotient.Unscaled(); ok {
+		return replicas
+	}
+	return math.MaxInt64
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"math"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func makeResourceList(cpu, memory string) corev1.ResourceList {
+	list := corev1.ResourceList{}
+	if cpu != "" {
+		list[corev1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		list[corev1.ResourceMemory] = resource.MustParse(memory)
+	}
+	return list
+}
+
+func TestComputePodResourceRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		containers     []corev1.ResourceList
+		initContainers []corev1.ResourceList
+		overhead       corev1.ResourceList
+
+		wantCPU    string
+		wantMemory string
+	}{
+		{
+			name:       "sum of containers",
+			containers: []corev1.ResourceList{makeResourceList("100m", "128Mi"), makeResourceList("200m", "256Mi")},
+			wantCPU:    "300m",
+			wantMemory: "384Mi",
+		},
+		{
+			name:           "init container larger than containers",
+			containers:     []corev1.ResourceList{makeResourceList("100m", "128Mi"), makeResourceList("200m", "256Mi")},
+			initContainers: []corev1.ResourceList{makeResourceList("1", ""), makeResourceList("500m", "1Gi")},
+			wantCPU:        "1",
+			wantMemory:     "1Gi",
+		},
+		{
+			name:       "pod overhead",
+			containers: []corev1.ResourceList{makeResourceList("100m", "128Mi")},
+			overhead:   makeResourceList("250m", "120Mi"),
+			wantCPU:    "350m",
+			wantMemory: "248Mi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Overhead: tt.overhead}}
+			for _, requests := range tt.containers {
+				pod.Spec.Containers = append(pod.Spec.Containers,
+					corev1.Container{Resources: corev1.ResourceRequirements{Requests: requests}})
+			}
+			for _, requests := range tt.initContainers {
+				pod.Spec.InitContainers = append(pod.Spec.InitContainers,
+					corev1.Container{Resources: corev1.ResourceRequirements{Requests: requests}})
+			}
+
+			got := ComputePodResourceRequest(pod)
+			if cpu := got[corev1.ResourceCPU]; cpu.Cmp(resource.MustParse(tt.wantCPU)) != 0 {
+				t.Errorf("ComputePodResourceRequest() cpu = %s, want %s", cpu.String(), tt.wantCPU)
+			}
+			if memory := got[corev1.ResourceMemory]; memory.Cmp(resource.MustParse(tt.wantMemory)) != 0 {
+				t.Errorf("ComputePodResourceRequest() memory = %s, want %s", memory.String(), tt.wantMemory)
+			}
+		})
+	}
+}
+
+func TestDivideQuantity(t *testing.T) {
+	tests := []struct {
+		value   string
+		divisor string
+		want    int64
+	}{
+		{value: "1", divisor: "100m", want: 10},
+		{value: "3500m", divisor: "250m", want: 14},
+		{value: "1", divisor: "300m", want: 3},
+		{value: "1536Mi", divisor: "1Gi", want: 1},
+		{value: "1.5Gi", divisor: "512Mi", want: 3},
+		{value: "100m", divisor: "1", want: 0},
+		{value: "0", divisor: "1", want: 0},
+		{value: "1", divisor: "0", want: 0},
+		// too large for milli units
+		{value: "100Pi", divisor: "1500m", want: 75059993789508266},
+		{value: "9000E", divisor: "1m", want: math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value+"/"+tt.divisor, func(t *testing.T) {
+			if got := DivideQuantity(resource.MustParse(tt.value), resource.MustParse(tt.divisor)); got != tt.want {
+				t.Errorf("DivideQuantity() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// LabelIndex indexes the names of nodes by label key and value. The names are sorted.
+type LabelIndex map[string]map[string][]string
+
+// Snapshot is an immutable view of the nodes of a cluster that a prediction runs against.
+type Snapshot struct {
+	// nodeInfos are sorted by node name.
+	nodeInfos []*NodeInfo
+	// havePodsWithRequiredAntiAffinity are the nodes with pods with required pod anti-affinity terms.
+	havePodsWithRequiredAntiAffinity []*NodeInfo
+
+	labelIndexOnce sync.Once
+	// labelIndex indexes the nodes by label key and value. It is built on first use when not given.
+	labelIndex LabelIndex
+
+	domainsMu sync.Mutex
+	// domains are the topology domains counted so far, keyed by label key.
+	domains map[string]*TopologyDomains
+}
+
+// NewSnapshot returns a snapshot of the NodeInfos. The label index of the nodes may be given when it is
+// maintained elsewhere, e.g. by the cache. Neither of them must be changed afterwards.
+func NewSnapshot(nodeInfos []*NodeInfo, labelIndex LabelIndex) *Snapshot {
+	s := &Snapshot{nodeInfos: nodeInfos, labelIndex: labelIndex}
+	if !sort.SliceIsSorted(nodeInfos, func(i, j int) bool { return nodeInfos[i].Node().Name < nodeInfos[j].Node().Name }) {
+		s.nodeInfos = make([]*NodeInfo, len(nodeInfos))
+		copy(s.nodeInfos, nodeInfos)
+		sort.Slice(s.nodeInfos, func(i, j int) bool { return s.nodeInfos[i].Node().Name < s.nodeInfos[j].Node().Name })
+	}
+	for _, nodeInfo := range s.nodeInfos {
+		if len(nodeInfo.PodsWithRequiredAntiAffinity()) != 0 {
+			s.havePodsWithRequiredAntiAffinity = append(s.havePodsWithRequiredAntiAffinity, nodeInfo)
+		}
+	}
+	return s
+}
+
+// Update returns a snapshot of the NodeInfos, which are those of s except for the nodes of the changed
+// names, and of the label index of them. The topology domains counted by s are carried over, with only
+// the pods of the changed nodes counted again.
+func (s *Snapshot) Update(nodeInfos []*NodeInfo, labelIndex LabelIndex, changed []string) *Snapshot {
+	updated := NewSnapshot(nodeInfos, labelIndex)
+	s.domainsMu.Lock()
+	domains := make(map[string]*TopologyDomains, len(s.domains))
+	for key, d := range s.domains {
+		domains[key] = d
+	}
+	s.domainsMu.Unlock()
+
+	if len(domains) != 0 {
+		updated.domains = make(map[string]*TopologyDomains, len(domains))
+		for key, d := range domains {
+			updated.domains[key] = updated.updateTopologyDomains(key, s, d, changed)
+		}
+	}
+	return updated
+}
+
+// List returns all the NodeInfos sorted by node name.
+func (s *Snapshot) List() []*NodeInfo {
+	return s.nodeInfos
+}
+
+// NumNodes returns the count of nodes in the snapshot.
+func (s *Snapshot) NumNodes() int {
+	return len(s.nodeInfos)
+}
+
+// Get returns the NodeInfo of the node, or nil if the node is not in the snapshot.
+func (s *Snapshot) Get(name string) *NodeInfo {
+	i := sort.Search(len(s.nodeInfos), func(i int) bool { return s.nodeInfos[i].Node().Name >= name })
+	if i < len(s.nodeInfos) && s.nodeInfos[i].Node().Name == name {
+		return s.nodeInfos[i]
+	}
+	return nil
+}
+
+// HavePodsWithRequiredAntiAffinityList returns the NodeInfos holding pods with required pod anti-affinity terms.
+func (s *Snapshot) HavePodsWithRequiredAntiAffinityList() []*NodeInfo {
+	return s.havePodsWithRequiredAntiAffinity
+}
+
+// NodesWithLabel returns the NodeInfos with the label, sorted by node name.
+func (s *Snapshot) NodesWithLabel(key, value string) []*NodeInfo {
+	names := s.index()[key][value]
+	nodeInfos := make([]*NodeInfo, 0, len(names))
+	for _, name := range names {
+		if nodeInfo := s.Get(name); nodeInfo != nil {
+			nodeInfos = append(nodeInfos, nodeInfo)
+		}
+	}
+	return nodeInfos
+}
+
+// WithoutPodsBelow returns a snapshot of the nodes without the pods and the assumed replicas of lower
+// priority than the given one, and whether any of them is left out. The snapshot itself is returned if
+// there is none.
+func (s *Snapshot) WithoutPodsBelow(priority int32) (*Snapshot, bool) {
+	var nodeInfos []*NodeInfo
+	var changed []string
+	for i, nodeInfo := range s.nodeInfos {
+		kept, podsLeftOut := keepPods(nodeInfo.Pods(), func(pod *corev1.Pod) bool { return PodPriority(pod) >= priority })
+		var keptAssumed []AssumedReplicas
+		assumedLeftOut := false
+		for _, assumed := range nodeInfo.AssumedReplicas() {
+			if PodPriority(assumed.Pod) >= priority {
+				keptAssumed = append(keptAssumed, assumed)
+			} else {
+				assumedLeftOut = true
+			}
+		}
+		if !podsLeftOut && !assumedLeftOut {
+			if nodeInfos != nil {
+				nodeInfos = append(nodeInfos, nodeInfo)
+			}
+			continue
+		}
+		// the nodes before are copied once the first node with such pods is found
+		if nodeInfos == nil {
+			nodeInfos = make([]*NodeInfo, i, len(s.nodeInfos))
+			copy(nodeInfos, s.nodeInfos[:i])
+		}
+		updated := NewNodeInfo(nodeInfo.Node(), kept...)
+		if len(keptAssumed) != 0 {
+			updated = updated.WithAssumedReplicas(keptAssumed...)
+		}
+		nodeInfos = append(nodeInfos, updated)
+		changed = append(changed, nodeInfo.Node().Name)
+	}
+	if nodeInfos == nil {
+		return s, false
+	}
+	// the nodes and their labels are the same
+	return s.Update(nodeInfos, s.index(), changed), true
+}
+
+// keepPods returns the pods kept by keep, and whether any pod is left out. The pods themselves are
+// returned if none is left out.
+func keepPods(pods []*corev1.Pod, keep func(pod *corev1.Pod) bool) ([]*corev1.Pod, bool) {
+	var kept []*corev1.Pod
+	for j, pod := range pods {
+		if keep(pod) {
+			if kept != nil {
+				kept = append(kept, pod)
+			}
+			continue
+		}
+		if kept == nil {
+			kept = make([]*corev1.Pod, j, len(pods))
+			copy(kept, pods[:j])
+		}
+	}
+	if kept == nil {
+		return pods, false
+	}
+	return kept, true
+}
+
+// WithAssumedReplicas returns a snapshot of the nodes with the replicas assumed to be placed on them,
+// keyed by node name. Only the NodeInfos of those nodes are built again, and the label index and the
+// topology domains of s are carried over. The snapshot itself is returned if there is no replica.
+func (s *Snapshot) WithAssumedReplicas(assumed map[string][]AssumedReplicas) *Snapshot {
+	var nodeInfos []*NodeInfo
+	var changed []string
+	for i, nodeInfo := range s.nodeInfos {
+		nodeAssumed := assumed[nodeInfo.Node().Name]
+		if len(nodeAssumed) == 0 {
+			if nodeInfos != nil {
+				nodeInfos = append(nodeInfos, nodeInfo)
+			}
+			continue
+		}
+		if nodeInfos == nil {
+			nodeInfos = make([]*NodeInfo, i, len(s.nodeInfos))
+			copy(nodeInfos, s.nodeInfos[:i])
+		}
+		nodeInfos = append(nodeInfos, nodeInfo.WithAssumedReplicas(nodeAssumed...))
+		changed = append(changed, nodeInfo.Node().Name)
+	}
+	if nodeInfos == nil {
+		return s
+	}
+	return s.Update(nodeInfos, s.index(), changed)
+}
+
+// index returns the label index of the nodes, which is built on first use when not given.
+func (s *Snapshot) index() LabelIndex {
+	s.labelIndexOnce.Do(func() {
+		if s.labelIndex != nil {
+			return
+		}
+		// the nodes are sorted, so are the names appended
+		s.labelIndex = make(LabelIndex)
+		for _, nodeInfo := range s.nodeInfos {
+			for k, v := range nodeInfo.Node().Labels {
+				values, ok := s.labelIndex[k]
+				if !ok {
+					values = make(map[string][]string)
+					s.labelIndex[k] = values
+				}
+				values[v] = append(values[v], nodeInfo.Node().Name)
+			}
+		}
+	})
+	return s.labelIndex
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSnapshotWithoutPodsBelow(t *testing.T) {
+	newPod := func(name string, priority *int32) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PodSpec{
+				Priority: priority,
+				Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+				}}},
+			},
+		}
+	}
+	low, high := int32(-10), int32(1000)
+	nodeInfos := []*NodeInfo{
+		NewNodeInfo(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"zone": "a"}}},
+			newPod("high", &high), newPod("default", nil)),
+		NewNodeInfo(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{"zone": "a"}}},
+			newPod("low", &low), newPod("high", &high), newPod("default", nil)),
+	}
+	snapshot := NewSnapshot(nodeInfos, nil)
+
+	tests := []struct {
+		name        string
+		priority    int32
+		wantChanged bool
+		// wantPods are the names of the pods kept on every node
+		wantPods map[string][]string
+	}{
+		{
+			name:     "no pod of lower priority",
+			priority: -10,
+			wantPods: map[string][]string{"node-1": {"high", "default"}, "node-2": {"low", "high", "default"}},
+		},
+		{
+			name:        "pods of lower priority on some nodes",
+			priority:    0,
+			wantChanged: true,
+			wantPods:    map[string][]string{"node-1": {"high", "default"}, "node-2": {"high", "default"}},
+		},
+		{
+			name:        "pods of lower priority on all nodes",
+			priority:    100,
+			wantChanged: true,
+			wantPods:    map[string][]string{"node-1": {"high"}, "node-2": {"high"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := snapshot.WithoutPodsBelow(tt.priority)
+			if changed != tt.wantChanged || (got == snapshot) == tt.wantChanged {
+				t.Fatalf("WithoutPodsBelow() changed = %v, want %v", changed, tt.wantChanged)
+			}
+			pods := make(map[string][]string)
+			for _, nodeInfo := range got.List() {
+				for _, pod := range nodeInfo.Pods() {
+					pods[nodeInfo.Node().Name] = append(pods[nodeInfo.Node().Name], pod.Name)
+				}
+				cpu := nodeInfo.Requested[corev1.ResourceCPU]
+				if cpu.Value() != int64(len(nodeInfo.Pods())) {
+					t.Errorf("WithoutPodsBelow() node %s requests %s cpu, want 1 per pod", nodeInfo.Node().Name, cpu.String())
+				}
+			}
+			if !reflect.DeepEqual(pods, tt.wantPods) {
+				t.Errorf("WithoutPodsBelow() pods = %v, want %v", pods, tt.wantPods)
+			}
+			if nodes := got.NodesWithLabel("zone", "a"); len(nodes) != 2 {
+				t.Errorf("WithoutPodsBelow() nodes with zone a = %d, want 2", len(nodes))
+			}
+		})
+	}
+}
+
+func TestSnapshotUpdateTopologyDomains(t *testing.T) {
+	newNode := func(name, zone string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"zone": zone}}}
+	}
+	newPod := func(name, app string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{"app": app}}}
+	}
+	snapshot := NewSnapshot([]*NodeInfo{
+		NewNodeInfo(newNode("node-1", "a"), newPod("web-1", "web"), newPod("db-1", "db")),
+		NewNodeInfo(newNode("node-2", "a"), newPod("web-2", "web")),
+		NewNodeInfo(newNode("node-3", "b"), newPod("web-3", "web")),
+	}, nil)
+	// count the domains before updating, so that they are carried over
+	snapshot.TopologyDomains("zone")
+
+	tests := []struct {
+		name      string
+		nodeInfos []*NodeInfo
+		changed   []string
+		wantNodes int
+		// wantPods are the counts of pods of every app in every zone
+		wantPods map[string]map[string]int64
+	}{
+		{
+			name: "pod added",
+			nodeInfos: []*NodeInfo{
+				NewNodeInfo(newNode("node-1", "a"), newPod("web-1", "web"), newPod("db-1", "db")),
+				NewNodeInfo(newNode("node-2", "a"), newPod("web-2", "web")),
+				NewNodeInfo(newNode("node-3", "b"), newPod("web-3", "web"), newPod("db-3", "db")),
+			},
+			changed:   []string{"node-3"},
+			wantNodes: 3,
+			wantPods:  map[string]map[string]int64{"a": {"web": 2, "db": 1}, "b": {"web": 1, "db": 1}},
+		},
+		{
+			name: "pods removed",
+			nodeInfos: []*NodeInfo{
+				NewNodeInfo(newNode("node-1", "a")),
+				NewNodeInfo(newNode("node-2", "a"), newPod("web-2", "web")),
+				NewNodeInfo(newNode("node-3", "b")),
+			},
+			changed:   []string{"node-1", "node-3"},
+			wantNodes: 3,
+			wantPods:  map[string]map[string]int64{"a": {"web": 1}, "b": {}},
+		},
+		{
+			name: "node moved to another zone",
+			nodeInfos: []*NodeInfo{
+				NewNodeInfo(newNode("node-1", "c"), newPod("web-1", "web"), newPod("db-1", "db")),
+				NewNodeInfo(newNode("node-2", "a"), newPod("web-2", "web")),
+				NewNodeInfo(newNode("node-3", "b"), newPod("web-3", "web")),
+			},
+			changed:   []string{"node-1"},
+			wantNodes: 3,
+			wantPods:  map[string]map[string]int64{"a": {"web": 1}, "b": {"web": 1}, "c": {"web": 1, "db": 1}},
+		},
+		{
+			name: "node deleted",
+			nodeInfos: []*NodeInfo{
+				NewNodeInfo(newNode("node-1", "a"), newPod("web-1", "web"), newPod("db-1", "db")),
+				NewNodeInfo(newNode("node-2", "a"), newPod("web-2", "web")),
+			},
+			changed:   []string{"node-3"},
+			wantNodes: 2,
+			wantPods:  map[string]map[string]int64{"a": {"web": 2, "db": 1}},
+		},
+	}
+
+	describe := func(domains *TopologyDomains) map[string]map[string]int64 {
+		pods := make(map[string]map[string]int64)
+		for value, groups := range domains.PodGroups {
+			pods[value] = make(map[string]int64)
+			for _, group := range groups {
+				pods[value][group.Labels["app"]] += group.Count
+			}
+		}
+		return pods
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := snapshot.Update(tt.nodeInfos, nil, tt.changed)
+			got := updated.TopologyDomains("zone")
+			if got.Nodes != tt.wantNodes {
+				t.Errorf("TopologyDomains() nodes = %d, want %d", got.Nodes, tt.wantNodes)
+			}
+			if pods := describe(got); !reflect.DeepEqual(pods, tt.wantPods) {
+				t.Errorf("TopologyDomains() pods = %v, want %v", pods, tt.wantPods)
+			}
+			// the carried over domains are the same as those counted from scratch
+			counted := NewSnapshot(tt.nodeInfos, nil).TopologyDomains("zone")
+			if !reflect.DeepEqual(describe(got), describe(counted)) {
+				t.Errorf("TopologyDomains() pods = %v, counted %v", describe(got), describe(counted))
+			}
+		})
+	}
+
+	// the domains of the original snapshot are unchanged by the updates
+	if pods := describe(snapshot.TopologyDomains("zone")); !reflect.DeepEqual(pods, map[string]map[string]int64{"a": {"web": 2, "db": 1}, "b": {"web": 1}}) {
+		t.Errorf("TopologyDomains() of the original snapshot pods = %v", pods)
+	}
+}
+
+func TestSnapshotWithAssumedReplicas(t *testing.T) {
+	newNode := func(name, zone string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"zone": zone}},
+			Status:     corev1.NodeStatus{Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100")}},
+		}
+	}
+	newPod := func(name string, priority int32) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{"app": name}},
+			Spec: corev1.PodSpec{
+				Priority: &priority,
+				Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				}}},
+			},
+		}
+	}
+	snapshot := NewSnapshot([]*NodeInfo{
+		NewNodeInfo(newNode("node-1", "a"), newPod("web", 0)),
+		NewNodeInfo(newNode("node-2", "b")),
+	}, nil)
+	snapshot.TopologyDomains("zone")
+
+	// a single pod stands for all the replicas however many they are
+	got := snapshot.WithAssumedReplicas(map[string][]AssumedReplicas{
+		"node-1": {{Pod: newPod("web", 0), Replicas: 100000}, {Pod: newPod("batch", -10), Replicas: 3}},
+		"node-3": {{Pod: newPod("web", 0), Replicas: 1}},
+	})
+	node1, node2 := got.Get("node-1"), got.Get("node-2")
+	if node2 != snapshot.Get("node-2") {
+		t.Errorf("WithAssumedReplicas() changed node-2 without assumed replicas")
+	}
+	if len(node1.Pods()) != 1 || node1.NumPods() != 100004 {
+		t.Errorf("WithAssumedReplicas() node-1 has %d pods and %d in all, want 1 and 100004", len(node1.Pods()), node1.NumPods())
+	}
+	if cpu, free := node1.Requested[corev1.ResourceCPU], node1.Free[corev1.ResourceCPU]; cpu.String() != "50002" || free.String() != "-49902" {
+		t.Errorf("WithAssumedReplicas() node-1 requests %s cpu with %s free, want 50002 and -49902", cpu.String(), free.String())
+	}
+	if nodes := got.NodesWithLabel("zone", "a"); len(nodes) != 1 || nodes[0] != node1 {
+		t.Errorf("WithAssumedReplicas() nodes with zone a = %v, want node-1", nodes)
+	}
+	if groups := got.TopologyDomains("zone").PodGroups["a"]; groups[PodGroupKey("default", map[string]string{"app": "web"})].Count != 100001 {
+		t.Errorf("WithAssumedReplicas() pod groups of zone a = %v, want 100001 web pods", groups)
+	}
+	if groups := snapshot.TopologyDomains("zone").PodGroups["a"]; groups[PodGroupKey("default", map[string]string{"app": "web"})].Count != 1 {
+		t.Errorf("WithAssumedReplicas() changed the pod groups of the original snapshot to %v", groups)
+	}
+
+	// the assumed replicas of lower priority are preempted as well
+	preempted, changed := got.WithoutPodsBelow(0)
+	if !changed || preempted.Get("node-1").NumPods() != 100001 {
+		t.Errorf("WithoutPodsBelow() changed = %v with %d pods on node-1, want 100001", changed, preempted.Get("node-1").NumPods())
+	}
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"encoding/json"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	appsapi "github.com/clusternet/clusternet/pkg/apis/apps/v1alpha1"
+)
+
+// Requirements describes the replicas of the workload to predict.
+type Requirements struct {
+	appsapi.ReplicaRequirements
+
+	// Namespace is the namespace the replicas are created in.
+	// Pod affinity terms without namespaces are matched against it.
+	Namespace string
+	// PodLabels are the labels of the replicas, matched by pod (anti-)affinity terms and topology spread constraints.
+	PodLabels map[string]string
+	// TopologySpreadConstraints are the topology spread constraints of the replicas.
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint
+	// Priority is the priority of the replicas. The pods of lower priority could be preempted for them,
+	// unless PreemptionPolicy is Never. Preemption is not predicted when it is nil.
+	Priority         *int32
+	PreemptionPolicy *corev1.PreemptionPolicy
+	// PriorityClassName is the PriorityClass of the replicas, matched by the quotas scoped by priority class.
+	PriorityClassName string
+}
+
+// CanPreempt checks whether the replicas could preempt the pods of lower priority.
+func (r *Requirements) CanPreempt() bool {
+	return r.Priority != nil && (r.PreemptionPolicy == nil || *r.PreemptionPolicy != corev1.PreemptNever)
+}
+
+// AssumedReplicas are the replicas that do not exist but are assumed to be placed on a node, e.g. the
+// replicas predicted for the earlier items of a joint batch prediction. Pod stands for every one of them,
+// so that they take up as little as a single pod however many they are.
+type AssumedReplicas struct {
+	Pod      *corev1.Pod `json:"pod"`
+	Replicas int64       `json:"replicas"`
+}
+
+// PodPriority returns the priority of the pod, which is 0 if it is not set.
+func PodPriority(pod *corev1.Pod) int32 {
+	if pod.Spec.Priority != nil {
+		return *pod.Spec.Priority
+	}
+	return 0
+}
+
+// NodeInfo is a node together with the pods bound to it. A NodeInfo is immutable once built,
+// so that it could be shared by the snapshots of the cache and by concurrent predictions.
+type NodeInfo struct {
+	node *corev1.Node
+	pods []*corev1.Pod
+	// podsWithRequiredAntiAffinity are the pods with required pod anti-affinity terms.
+	podsWithRequiredAntiAffinity []*corev1.Pod
+	// assumed are the replicas assumed to be placed on the node, which are not in pods.
+	assumed []AssumedReplicas
+	// numPods is the count of pods and assumed replicas.
+	numPods int64
+
+	podGroupsOnce sync.Once
+	// podGroups group the pods not being deleted, built on first use.
+	podGroups map[string]PodGroup
+
+	// Requested is the sum of resources requested by all non-terminal pods on the node.
+	Requested corev1.ResourceList
+	// Free is the allocatable resources of the node minus Requested. It may be negative when
+	// the node is overcommitted, e.g. when its allocatable resources shrink.
+	Free corev1.ResourceList
+}
+
+// NewNodeInfo returns a NodeInfo of the node with the given pods.
+func NewNodeInfo(node *corev1.Node, pods ...*corev1.Pod) *NodeInfo {
+	ni := &NodeInfo{
+		node:      node,
+		Requested: corev1.ResourceList{},
+	}
+	for _, pod := range pods {
+		if IsPodTerminal(pod) {
+			continue
+		}
+		ni.pods = append(ni.pods, pod)
+		if hasRequiredPodAntiAffinity(pod) {
+			ni.podsWithRequiredAntiAffinity = append(ni.podsWithRequiredAntiAffinity, pod)
+		}
+		AddResourceList(ni.Requested, ComputePodResourceRequest(pod))
+	}
+	ni.numPods = int64(len(ni.pods))
+
+	ni.Free = node.Status.Allocatable.DeepCopy()
+	if ni.Free == nil {
+		ni.Free = corev1.ResourceList{}
+	}
+	for name, requested := range ni.Requested {
+		free := ni.Free[name]
+		free.Sub(requested)
+		ni.Free[name] = free
+	}
+	return ni
+}
+
+// WithAssumedReplicas returns a copy of the NodeInfo with the replicas assumed to be placed on the node.
+// The pods are shared with n, and only the resources of the replicas are added.
+func (n *NodeInfo) WithAssumedReplicas(assumed ...AssumedReplicas) *NodeInfo {
+	ni := &NodeInfo{
+		node: n.node,
+		pods: n.pods,
+		// the capacity is limited, so that appending never writes to the array shared with n
+		podsWithRequiredAntiAffinity: n.podsWithRequiredAntiAffinity[:len(n.podsWithRequiredAntiAffinity):len(n.podsWithRequiredAntiAffinity)],
+		assumed:                      make([]AssumedReplicas, 0, len(n.assumed)+len(assumed)),
+		numPods:                      n.numPods,
+		Requested:                    n.Requested.DeepCopy(),
+		Free:                         n.Free.DeepCopy(),
+	}
+	ni.assumed = append(ni.assumed, n.assumed...)
+	for _, a := range assumed {
+		if a.Replicas <= 0 || IsPodTerminal(a.Pod) {
+			continue
+		}
+		ni.assumed = append(ni.assumed, a)
+		ni.numPods += a.Replicas
+		// a single pod of the replicas stands for the domains blocked by their anti-affinity terms
+		if hasRequiredPodAntiAffinity(a.Pod) {
+			ni.podsWithRequiredAntiAffinity = append(ni.podsWithRequiredAntiAffinity, a.Pod)
+		}
+		for name, request := range ComputePodResourceRequest(a.Pod) {
+			requested := MultiplyQuantity(request, a.Replicas)
+			AddResourceList(ni.Requested, corev1.ResourceList{name: requested})
+			free := ni.Free[name]
+			free.Sub(requested)
+			ni.Free[name] = free
+		}
+	}
+	return ni
+}
+
+// hasRequiredPodAntiAffinity checks whether the pod has required pod anti-affinity terms.
+func hasRequiredPodAntiAffinity(pod *corev1.Pod) bool {
+	return pod.Spec.Affinity != nil && pod.Spec.Affinity.PodAntiAffinity != nil &&
+		len(pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) != 0
+}
+
+// Node returns the node.
+func (n *NodeInfo) Node() *corev1.Node {
+	return n.node
+}
+
+// Pods returns the non-terminal pods on the node, without the assumed replicas.
+func (n *NodeInfo) Pods() []*corev1.Pod {
+	return n.pods
+}
+
+// NumPods returns the count of the non-terminal pods and the assumed replicas on the node.
+func (n *NodeInfo) NumPods() int64 {
+	return n.numPods
+}
+
+// PodsWithRequiredAntiAffinity returns the non-terminal pods with required pod anti-affinity terms,
+// including a pod of every assumed replicas with such terms.
+func (n *NodeInfo) PodsWithRequiredAntiAffinity() []*corev1.Pod {
+	return n.podsWithRequiredAntiAffinity
+}
+
+// AssumedReplicas returns the replicas assumed to be placed on the node.
+func (n *NodeInfo) AssumedReplicas() []AssumedReplicas {
+	return n.assumed
+}
+
+// PodGroups returns the pods not being deleted and the assumed replicas, grouped by namespace and labels
+// and keyed by PodGroupKey.
+func (n *NodeInfo) PodGroups() map[string]PodGroup {
+	n.podGroupsOnce.Do(func() {
+		n.podGroups = make(map[string]PodGroup)
+		add := func(pod *corev1.Pod, count int64) {
+			key := PodGroupKey(pod.Namespace, pod.Labels)
+			group, ok := n.podGroups[key]
+			if !ok {
+				group = PodGroup{Namespace: pod.Namespace, Labels: pod.Labels}
+			}
+			group.Count += count
+			n.podGroups[key] = group
+		}
+		for _, pod := range n.pods {
+			if pod.DeletionTimestamp == nil {
+				add(pod, 1)
+			}
+		}
+		for _, a := range n.assumed {
+			add(a.Pod, a.Replicas)
+		}
+	})
+	return n.podGroups
+}
+
+// PodGroup is the count of pods of the same namespace and labels, so that a selector matching all of them
+// only needs to be matched once, e.g. against the pods of a workload.
+type PodGroup struct {
+	Namespace string
+	Labels    map[string]string
+	Count     int64
+}
+
+// PodGroupKey returns the key of the pods of the namespace and labels.
+func PodGroupKey(namespace string, podLabels map[string]string) string {
+	return namespace + "/" + labels.Set(podLabels).String()
+}
+
+// Plugins enables the plugins of each extension point. Plugins are run in the given order.
+type Plugins struct {
+	Filter   []string `json:"filter,omitempty"`
+	Estimate []string `json:"estimate,omitempty"`
+	Cap      []string `json:"cap,omitempty"`
+}
+
+// PluginConfig holds the arguments passed to a plugin when it is built.
+type PluginConfig struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/component-base/version"
+	"k8s.io/klog/v2"
+)
+
+// apiServerCheckTimeout bounds the time a readiness probe waits for the API server.
+const apiServerCheckTimeout = 5 * time.Second
+
+// Healthz is a http handler for liveness probes. It succeeds as long as the server is serving.
+func (p *PredictorServer) Healthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentTypeText)
+	fmt.Fprint(w, "ok")
+}
+
+// Readyz is a http handler for readiness probes. The predictor is ready once the informer caches
+// have synced and the API server is reachable, or once the clusters are loaded when it serves multiple clusters.
+func (p *PredictorServer) Readyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentTypeText)
+	if err := p.checkReady(r.Context()); err != nil {
+		klog.V(2).Infof("predictor is not ready: %v", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "not ready: %v", err)
+		return
+	}
+	fmt.Fprint(w, "ok")
+}
+
+// Version is a http handler that reports the build info of the predictor.
+func (p *PredictorServer) Version(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentTypeJSON)
+	if err := json.NewEncoder(w).Encode(version.Get()); err != nil {
+		klog.Error(err)
+	}
+}
+
+func (p *PredictorServer) checkReady(ctx context.Context) error {
+	if atomic.LoadInt32(&p.synced) == 0 {
+		return fmt.Errorf("informer caches have not synced yet")
+	}
+	// every cluster reports its own readiness
+	if p.clusters != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, apiServerCheckTimeout)
+	defer cancel()
+	_, err := p.k8sClient.Discovery().RESTClient().Get().AbsPath("/healthz").Do(ctx).Raw()
+	if err != nil {
+		return fmt.Errorf("error of reach the API server : %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const subsystem = "predictor"
+
+// Every metric is labeled by the cluster it is about, which is empty unless the predictor serves multiple
+// clusters, so that the clusters sharing the metrics of a predictor are told apart.
+var (
+	// RequestsTotal counts the requests served by the predictor.
+	RequestsTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      subsystem,
+			Name:           "http_requests_total",
+			Help:           "Number of requests served, partitioned by cluster, endpoint and http code.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"cluster", "endpoint", "code"})
+
+	// RequestDuration is the latency of the requests served by the predictor.
+	RequestDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      subsystem,
+			Name:           "http_request_duration_seconds",
+			Help:           "Latency of the requests in seconds, partitioned by cluster and endpoint.",
+			Buckets:        metrics.ExponentialBuckets(0.001, 2, 15),
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"cluster", "endpoint"})
+
+	// PredictedReplicas is the distribution of the max acceptable replicas returned by the predictor.
+	PredictedReplicas = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      subsystem,
+			Name:           "predicted_replicas",
+			Help:           "Distribution of the predicted max acceptable replicas, partitioned by cluster.",
+			Buckets:        append([]float64{0}, metrics.ExponentialBuckets(1, 2, 16)...),
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"cluster"})
+
+	// RejectedNodesTotal counts the nodes rejected by the plugins. The reasons are left out, as they may
+	// hold anything, e.g. the keys and values of node taints.
+	RejectedNodesTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      subsystem,
+			Name:           "rejected_nodes_total",
+			Help:           "Number of nodes rejected by plugins, partitioned by cluster and plugin.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"cluster", "plugin"})
+
+	// InformerSynced tells whether the cache of an informer has synced.
+	InformerSynced = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      subsystem,
+			Name:           "informer_synced",
+			Help:           "Whether the cache of an informer has synced, 1 for synced and 0 otherwise, partitioned by cluster and informer.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"cluster", "informer"})
+
+	// NodeCacheAge is the time since the node cache last changed.
+	NodeCacheAge = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      subsystem,
+			Name:           "node_cache_age_seconds",
+			Help:           "Seconds since the node cache last received an event, partitioned by cluster.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"cluster"})
+
+	// Reservations is the count of the active capacity reservations.
+	Reservations = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      subsystem,
+			Name:           "reservations",
+			Help:           "Number of active capacity reservations, partitioned by cluster.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"cluster"})
+
+	// ReservedReplicas is the replicas held by the active capacity reservations.
+	ReservedReplicas = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      subsystem,
+			Name:           "reserved_replicas",
+			Help:           "Number of replicas reserved by the active capacity reservations and not yet taken up by pods, partitioned by cluster.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"cluster"})
+
+	nodeCacheMu sync.Mutex
+	// nodeCacheUpdated is the time the node cache of every cluster last received an event.
+	nodeCacheUpdated = make(map[string]time.Time)
+
+	registerOnce sync.Once
+)
+
+// Register registers all the metrics of the predictor.
+func Register() {
+	registerOnce.Do(func() {
+		legacyregistry.MustRegister(
+			RequestsTotal,
+			RequestDuration,
+			PredictedReplicas,
+			RejectedNodesTotal,
+			InformerSynced,
+			NodeCacheAge,
+			Reservations,
+			ReservedReplicas,
+		)
+	})
+}
+
+// NodeCacheUpdated records that the node cache of the cluster has just received an event.
+func NodeCacheUpdated(cluster string) {
+	nodeCacheMu.Lock()
+	defer nodeCacheMu.Unlock()
+	nodeCacheUpdated[cluster] = time.Now()
+}
+
+// ForgetCluster removes the gauges of a cluster no longer served, so that they are not reported as
+// they were when it was stopped. The counters and histograms are kept, as they only ever grow.
+func ForgetCluster(cluster string) {
+	nodeCacheMu.Lock()
+	delete(nodeCacheUpdated, cluster)
+	nodeCacheMu.Unlock()
+	labels := map[string]string{"cluster": cluster}
+	NodeCacheAge.Delete(labels)
+	Reservations.Delete(labels)
+	ReservedReplicas.Delete(labels)
+}
+
+// Handler returns a http handler that serves the metrics.
+func Handler() http.Handler {
+	handler := legacyregistry.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the age keeps growing between events, so it is computed at scrape time
+		nodeCacheMu.Lock()
+		for cluster, updated := range nodeCacheUpdated {
+			NodeCacheAge.WithLabelValues(cluster).Set(time.Since(updated).Seconds())
+		}
+		nodeCacheMu.Unlock()
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// SinceInSeconds gets the time since the specified start in seconds.
+func SinceInSeconds(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
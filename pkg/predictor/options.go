@@ -13,9 +13,93 @@ limitations under the License.
 
 package predictor
 
+import (
+	"fmt"
+	"time"
+)
+
 // PredictorOptions is options for predictor
 type PredictorOptions struct {
 	MasterURL      string
 	KubeconfigPath string
-	Port           uint
+	// ClustersKubeconfig serves multiple clusters, loaded from a directory holding a kubeconfig per cluster,
+	// or from a kubeconfig holding a context per cluster. MasterURL and KubeconfigPath are then only used
+	// to review the tokens and the access of callers.
+	ClustersKubeconfig string
+	Port               uint
+	// MaxRequestBytes is the max size of a request body.
+	MaxRequestBytes int64
+	// ConfigFile is the path of the predictor configuration file, which enables and configures plugins.
+	ConfigFile string
+	// ShutdownGracePeriod is how long in-flight requests are waited for when the predictor is stopped.
+	ShutdownGracePeriod time.Duration
+
+	// TLSCertFile and TLSPrivateKeyFile serve the predictor over https. They are reloaded when they change on disk.
+	TLSCertFile       string
+	TLSPrivateKeyFile string
+	// ClientCAFile verifies the client certificates of the requests.
+	ClientCAFile string
+	// RequireClientCert rejects the connections without a valid client certificate.
+	RequireClientCert bool
+
+	// TokenAuthFile is a csv file of static bearer tokens, in the format of "token,user,uid,\"group1,group2\"".
+	TokenAuthFile string
+	// AuthenticationTokenWebhook authenticates bearer tokens with the TokenReview API.
+	AuthenticationTokenWebhook bool
+	// AuthorizationMode is either AlwaysAllow or Webhook, which authorizes callers with the SubjectAccessReview API.
+	AuthorizationMode string
+	// AuthorizationVerb, AuthorizationGroup and AuthorizationResource are the attributes callers are authorized
+	// against, while the name of the resource is the endpoint, e.g. "accept".
+	AuthorizationVerb     string
+	AuthorizationGroup    string
+	AuthorizationResource string
+
+	// RecordDir records every prediction, with the nodes and pods it was made against, to the directory.
+	// Predictions are not recorded when it is empty.
+	RecordDir string
+	// RecordMaxFiles is the count of the latest records kept in RecordDir.
+	RecordMaxFiles int
+
+	// ReservationTTL is how long the replicas predicted for a request with a reservation id are reserved.
+	// Reservations are disabled when it is 0.
+	ReservationTTL time.Duration
+	// ReservationMaxReplicas is the most replicas a reservation holds, so that a single caller could not
+	// take up the whole cluster. There is no limit when it is 0.
+	ReservationMaxReplicas int64
+}
+
+// Validate checks whether the options are consistent.
+func (o *PredictorOptions) Validate() error {
+	if o.MaxRequestBytes <= 0 {
+		return fmt.Errorf("--max-request-bytes must be positive")
+	}
+	if (o.TLSCertFile == "") != (o.TLSPrivateKeyFile == "") {
+		return fmt.Errorf("--tls-cert-file and --tls-private-key-file must be specified together")
+	}
+	if o.ClientCAFile != "" && o.TLSCertFile == "" {
+		return fmt.Errorf("--client-ca-file requires --tls-cert-file and --tls-private-key-file")
+	}
+	if o.RequireClientCert && o.ClientCAFile == "" {
+		return fmt.Errorf("--require-client-cert requires --client-ca-file")
+	}
+	switch o.AuthorizationMode {
+	case "", AuthorizationModeAlwaysAllow, AuthorizationModeWebhook:
+	default:
+		return fmt.Errorf("unknown authorization mode %q, must be %s or %s",
+			o.AuthorizationMode, AuthorizationModeAlwaysAllow, AuthorizationModeWebhook)
+	}
+	if o.AuthorizationMode == AuthorizationModeWebhook && (o.AuthorizationVerb == "" || o.AuthorizationResource == "") {
+		return fmt.Errorf("--authorization-verb and --authorization-resource are required by the %s authorization mode",
+			AuthorizationModeWebhook)
+	}
+	if o.RecordDir != "" && o.RecordMaxFiles <= 0 {
+		return fmt.Errorf("--record-max-files must be positive with --record-dir")
+	}
+	if o.ReservationTTL < 0 {
+		return fmt.Errorf("--reservation-ttl must not be negative")
+	}
+	if o.ReservationMaxReplicas < 0 {
+		return fmt.Errorf("--reservation-max-replicas must not be negative")
+	}
+	return nil
 }
@@ -0,0 +1,210 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+// ClusterResourceCapsName is the name of the plugin used in the plugin registry and configurations.
+const ClusterResourceCapsName = "ClusterResourceCaps"
+
+// CapSource is where the value of a cap is read from.
+type CapSource string
+
+const (
+	// CapSourceLabel reads the value from a node label.
+	CapSourceLabel CapSource = "Label"
+	// CapSourceAnnotation reads the value from a node annotation.
+	CapSourceAnnotation CapSource = "Annotation"
+)
+
+// CapScope is the scope of the value of a cap.
+type CapScope string
+
+const (
+	// CapScopeNode means every node reports its own value, and the values are summed.
+	CapScopeNode CapScope = "Node"
+	// CapScopeCluster means the value is shared by the whole cluster, e.g. the free IPs of a subnet,
+	// and the smallest value reported by any node is used.
+	CapScopeCluster CapScope = "Cluster"
+)
+
+// ResourceCap describes a cluster-level resource that bounds the replicas.
+type ResourceCap struct {
+	// Name is used in logs and results.
+	Name string `json:"name"`
+	// Source is where the value is read from, either Label or Annotation.
+	Source CapSource `json:"source"`
+	// Key is the label or annotation key on the nodes. The value must be a quantity.
+	Key string `json:"key"`
+	// Scope is either Node or Cluster.
+	Scope CapScope `json:"scope"`
+	// UnitsPerReplica is the units of the value consumed by each replica. Defaults to 1.
+	UnitsPerReplica int64 `json:"unitsPerReplica,omitempty"`
+	// ResourceName, when set, makes each replica consume its request of the resource instead of
+	// UnitsPerReplica. The cap does not apply to workloads not requesting the resource.
+	ResourceName corev1.ResourceName `json:"resourceName,omitempty"`
+}
+
+// ClusterResourceCapsArgs holds arguments used to configure the ClusterResourceCaps plugin.
+type ClusterResourceCapsArgs struct {
+	Caps []ResourceCap `json:"caps"`
+}
+
+// ClusterResourceCaps is a cap plugin that bounds the replicas by resources reported on node
+// labels or annotations.
+type ClusterResourceCaps struct {
+	caps []ResourceCap
+}
+
+var _ framework.CapPlugin = &ClusterResourceCaps{}
+
+// NewClusterResourceCaps returns a ClusterResourceCaps plugin.
+func NewClusterResourceCaps(rawArgs json.RawMessage, _ framework.Handle) (framework.Plugin, error) {
+	var args ClusterResourceCapsArgs
+	if len(rawArgs) != 0 {
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range args.Caps {
+		c := &args.Caps[i]
+		if c.Key == "" {
+			return nil, fmt.Errorf("key of cap %q is required", c.Name)
+		}
+		if c.Source != CapSourceLabel && c.Source != CapSourceAnnotation {
+			return nil, fmt.Errorf("source of cap %q must be %s or %s", c.Name, CapSourceLabel, CapSourceAnnotation)
+		}
+		if c.Scope != CapScopeNode && c.Scope != CapScopeCluster {
+			return nil, fmt.Errorf("scope of cap %q must be %s or %s", c.Name, CapScopeNode, CapScopeCluster)
+		}
+		if c.UnitsPerReplica < 0 {
+			return nil, fmt.Errorf("unitsPerReplica of cap %q must not be negative", c.Name)
+		}
+		if c.UnitsPerReplica == 0 {
+			c.UnitsPerReplica = 1
+		}
+		if c.Name == "" {
+			c.Name = c.Key
+		}
+	}
+	return &ClusterResourceCaps{caps: args.Caps}, nil
+}
+
+// Name returns name of the plugin.
+func (pl *ClusterResourceCaps) Name() string {
+	return ClusterResourceCapsName
+}
+
+// Cap returns the smallest replicas allowed by the configured caps. Caps that have no data on any
+// node, or that do not apply to the requirements, do not constrain anything.
+func (pl *ClusterResourceCaps) Cap(_ context.Context, _ *framework.CycleState, requirements *framework.Requirements, nodeInfos []*framework.NodeInfo, estimates map[string]int64) (int64, *framework.Status) {
+	var result int64
+	var capName string
+	for i := range pl.caps {
+		replicas, ok := pl.capReplicas(&pl.caps[i], requirements, nodeInfos, estimates)
+		if !ok {
+			continue
+		}
+		klog.V(5).Infof("cap %s allows %d replicas", pl.caps[i].Name, replicas)
+		if capName == "" || replicas < result {
+			result = replicas
+			capName = pl.caps[i].Name
+		}
+	}
+	if capName == "" {
+		return 0, framework.NewStatus(framework.Skip)
+	}
+	return result, framework.NewStatus(framework.Success, fmt.Sprintf("cluster resource %s allows %d replicas", capName, result))
+}
+
+// capReplicas returns the replicas allowed by the cap, and false if the cap does not apply.
+func (pl *ClusterResourceCaps) capReplicas(c *ResourceCap, requirements *framework.Requirements,
+	nodeInfos []*framework.NodeInfo, estimates map[string]int64) (int64, bool) {
+	perReplica := resource.NewQuantity(c.UnitsPerReplica, resource.DecimalSI)
+	if c.ResourceName != "" {
+		request, ok := requirements.Resources.Requests[c.ResourceName]
+		if !ok || request.IsZero() {
+			return 0, false
+		}
+		perReplica = &request
+	}
+
+	found := false
+	var clusterValue resource.Quantity
+	var sum int64
+	for _, nodeInfo := range nodeInfos {
+		node := nodeInfo.Node()
+		estimate := estimates[node.Name]
+		value, ok := readCapValue(c, node)
+		if !ok {
+			// a node without data is only bounded by its own estimate
+			sum = saturatingAdd(sum, estimate)
+			continue
+		}
+
+		if c.Scope == CapScopeCluster {
+			if !found || value.Cmp(clusterValue) < 0 {
+				clusterValue = value
+			}
+		} else {
+			replicas := framework.DivideQuantity(value, *perReplica)
+			if replicas > estimate {
+				replicas = estimate
+			}
+			sum = saturatingAdd(sum, replicas)
+		}
+		found = true
+	}
+
+	if !found {
+		return 0, false
+	}
+	if c.Scope == CapScopeCluster {
+		return framework.DivideQuantity(clusterValue, *perReplica), true
+	}
+	return sum, true
+}
+
+// readCapValue reads the value of the cap from the node, and returns false if it is missing or invalid.
+func readCapValue(c *ResourceCap, node *corev1.Node) (resource.Quantity, bool) {
+	var raw string
+	var ok bool
+	switch c.Source {
+	case CapSourceLabel:
+		raw, ok = node.Labels[c.Key]
+	case CapSourceAnnotation:
+		raw, ok = node.Annotations[c.Key]
+	}
+	if !ok {
+		return resource.Quantity{}, false
+	}
+
+	value, err := resource.ParseQuantity(raw)
+	if err != nil {
+		klog.V(4).Infof("invalid value %q of %s on node %s: %v", raw, c.Key, node.Name, err)
+		return resource.Quantity{}, false
+	}
+	return value, true
+}
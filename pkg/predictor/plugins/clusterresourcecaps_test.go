@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+func TestClusterResourceCaps(t *testing.T) {
+	const ipKey = "tke.cloud.tencent.com/available-ip-count"
+	const gpuKey = "example.com/cluster-free-gpu"
+
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{ipKey: "3"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{ipKey: "10"}, Annotations: map[string]string{gpuKey: "8"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-3", Annotations: map[string]string{gpuKey: "6"}}},
+	}
+	nodeInfos := make([]*framework.NodeInfo, 0, len(nodes))
+	for _, node := range nodes {
+		nodeInfos = append(nodeInfos, framework.NewNodeInfo(node))
+	}
+	estimates := map[string]int64{"node-1": 5, "node-2": 4, "node-3": 2}
+
+	ipCap := ResourceCap{Name: "ip", Source: CapSourceLabel, Key: ipKey, Scope: CapScopeNode}
+	gpuCap := ResourceCap{Name: "gpu", Source: CapSourceAnnotation, Key: gpuKey, Scope: CapScopeCluster, ResourceName: "nvidia.com/gpu"}
+	missingCap := ResourceCap{Name: "missing", Source: CapSourceLabel, Key: "example.com/missing", Scope: CapScopeCluster}
+
+	tests := []struct {
+		name     string
+		caps     []ResourceCap
+		requests corev1.ResourceList
+		// estimates replace the estimates of the nodes when set
+		estimates map[string]int64
+
+		wantReplicas int64
+		wantSkip     bool
+	}{
+		{
+			name: "per node values bound their own node",
+			caps: []ResourceCap{ipCap},
+			// node-1: min(5, 3), node-2: min(4, 10), node-3 has no data: 2
+			wantReplicas: 9,
+		},
+		{
+			name:     "cluster value divided by the resource request",
+			caps:     []ResourceCap{gpuCap},
+			requests: corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("2")},
+			// the smallest value 6 is used
+			wantReplicas: 3,
+		},
+		{
+			name:     "smallest of the caps",
+			caps:     []ResourceCap{ipCap, gpuCap},
+			requests: corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("4")},
+			// gpu: 6 / 4
+			wantReplicas: 1,
+		},
+		{
+			name:      "unbounded estimates saturate",
+			caps:      []ResourceCap{ipCap},
+			estimates: map[string]int64{"node-1": math.MaxInt64, "node-2": math.MaxInt64, "node-3": math.MaxInt64},
+			// node-3 has no data and is only bounded by its own estimate
+			wantReplicas: math.MaxInt64,
+		},
+		{
+			name:     "cap on a resource not requested",
+			caps:     []ResourceCap{gpuCap},
+			wantSkip: true,
+		},
+		{
+			name:     "cap without data",
+			caps:     []ResourceCap{missingCap},
+			wantSkip: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl := &ClusterResourceCaps{caps: tt.caps}
+			for i := range pl.caps {
+				pl.caps[i].UnitsPerReplica = 1
+			}
+			requirements := &framework.Requirements{}
+			requirements.Resources.Requests = tt.requests
+			nodeEstimates := estimates
+			if tt.estimates != nil {
+				nodeEstimates = tt.estimates
+			}
+
+			replicas, status := pl.Cap(context.TODO(), framework.NewCycleState(), requirements, nodeInfos, nodeEstimates)
+			if status.IsSkip() != tt.wantSkip {
+				t.Fatalf("Cap() status = %v, want skip %v", status.Code(), tt.wantSkip)
+			}
+			if !tt.wantSkip && replicas != tt.wantReplicas {
+				t.Errorf("Cap() replicas = %d, want %d", replicas, tt.wantReplicas)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/client-go/listers/core/v1"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+const (
+	// InterPodAffinityName is the name of the plugin used in the plugin registry and configurations.
+	InterPodAffinityName = "InterPodAffinity"
+
+	preFilterStateKeyInterPodAffinity = "PreFilter" + InterPodAffinityName
+
+	errReasonAntiAffinityRulesNotMatch         = "node(s) didn't match pod anti-affinity rules"
+	errReasonExistingAntiAffinityRulesNotMatch = "node(s) didn't satisfy existing pods anti-affinity rules"
+)
+
+// InterPodAffinity applies the required pod anti-affinity of the workload, as well as the required
+// pod anti-affinity of the existing pods. Preferred terms and pod affinity are not predicted.
+//
+// Nodes in a topology domain holding pods that repel the workload are filtered out. When a term
+// of the workload selects the workload itself, every topology domain of the term holds at most one
+// replica, e.g. one replica per node with the "kubernetes.io/hostname" topology key.
+type InterPodAffinity struct {
+	namespaceLister corelisters.NamespaceLister
+}
+
+var _ framework.PreFilterPlugin = &InterPodAffinity{}
+var _ framework.FilterPlugin = &InterPodAffinity{}
+var _ framework.EstimatePlugin = &InterPodAffinity{}
+var _ framework.CapPlugin = &InterPodAffinity{}
+
+// NewInterPodAffinity returns an InterPodAffinity plugin.
+func NewInterPodAffinity(_ json.RawMessage, handle framework.Handle) (framework.Plugin, error) {
+	return &InterPodAffinity{
+		// namespaces are needed to match terms with a namespace selector
+		namespaceLister: handle.SharedInformerFactory().Core().V1().Namespaces().Lister(),
+	}, nil
+}
+
+// Name returns name of the plugin.
+func (pl *InterPodAffinity) Name() string {
+	return InterPodAffinityName
+}
+
+// topologyPair is a topology domain, e.g. zone=zone-a.
+type topologyPair struct {
+	key   string
+	value string
+}
+
+// affinityTerm is a parsed pod affinity term.
+type affinityTerm struct {
+	namespaces        sets.String
+	namespaceSelector labels.Selector
+	selector          labels.Selector
+	topologyKey       string
+}
+
+// newAffinityTerm parses the term of a pod in the given namespace.
+func newAffinityTerm(namespace string, term *corev1.PodAffinityTerm) (*affinityTerm, error) {
+	selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	// same as kube-scheduler, a term without namespaces and namespace selector selects the namespace of the pod
+	namespaces := sets.NewString(term.Namespaces...)
+	namespaceSelector := labels.Nothing()
+	if term.NamespaceSelector != nil {
+		namespaceSelector, err = metav1.LabelSelectorAsSelector(term.NamespaceSelector)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(term.Namespaces) == 0 {
+		namespaces.Insert(namespace)
+	}
+	return &affinityTerm{
+		namespaces:        namespaces,
+		namespaceSelector: namespaceSelector,
+		selector:          selector,
+		topologyKey:       term.TopologyKey,
+	}, nil
+}
+
+// matches checks whether the term selects a pod with the given namespace and labels.
+func (t *affinityTerm) matches(namespace string, namespaceLabels, podLabels labels.Set) bool {
+	if !t.namespaces.Has(namespace) && !t.namespaceSelector.Matches(namespaceLabels) {
+		return false
+	}
+	return t.selector.Matches(podLabels)
+}
+
+// interPodAffinityState is computed at PreFilter and read back at the other extension points.
+type interPodAffinityState struct {
+	// blockedDomains are the domains holding pods selected by an anti-affinity term of the workload.
+	blockedDomains map[topologyPair]bool
+	// existingBlockedDomains are the domains holding pods whose anti-affinity selects the workload.
+	existingBlockedDomains map[topologyPair]bool
+	// selfTerms are the anti-affinity terms of the workload selecting the workload itself.
+	selfTerms []*affinityTerm
+}
+
+// PreFilter collects the topology domains the workload can not be placed in.
+func (pl *InterPodAffinity) PreFilter(_ context.Context, state *framework.CycleState, requirements *framework.Requirements, snapshot *framework.Snapshot) *framework.Status {
+	s := &interPodAffinityState{
+		blockedDomains:         make(map[topologyPair]bool),
+		existingBlockedDomains: make(map[topologyPair]bool),
+	}
+	state.Write(preFilterStateKeyInterPodAffinity, s)
+
+	var terms []*affinityTerm
+	if requirements.Affinity != nil && requirements.Affinity.PodAntiAffinity != nil {
+		for i := range requirements.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			term, err := newAffinityTerm(requirements.Namespace,
+				&requirements.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i])
+			if err != nil {
+				return framework.AsStatus(fmt.Errorf("invalid pod anti-affinity term: %v", err))
+			}
+			terms = append(terms, term)
+		}
+	}
+
+	namespaceLabels, err := pl.getNamespaceLabels(requirements.Namespace)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	podLabels := labels.Set(requirements.PodLabels)
+	for _, term := range terms {
+		if term.matches(requirements.Namespace, namespaceLabels, podLabels) {
+			s.selfTerms = append(s.selfTerms, term)
+		}
+	}
+
+	if len(terms) != 0 {
+		for _, nodeInfo := range snapshot.List() {
+			nodeLabels := nodeInfo.Node().Labels
+			pods := nodeInfo.Pods()
+			if assumed := nodeInfo.AssumedReplicas(); len(assumed) != 0 {
+				// a single pod of the assumed replicas stands for all of them
+				pods = make([]*corev1.Pod, 0, len(nodeInfo.Pods())+len(assumed))
+				pods = append(pods, nodeInfo.Pods()...)
+				for _, a := range assumed {
+					pods = append(pods, a.Pod)
+				}
+			}
+			for _, pod := range pods {
+				existingNamespaceLabels, err := pl.getNamespaceLabels(pod.Namespace)
+				if err != nil {
+					return framework.AsStatus(err)
+				}
+				for _, term := range terms {
+					value, ok := nodeLabels[term.topologyKey]
+					if ok && term.matches(pod.Namespace, existingNamespaceLabels, pod.Labels) {
+						s.blockedDomains[topologyPair{key: term.topologyKey, value: value}] = true
+					}
+				}
+			}
+		}
+	}
+
+	for _, nodeInfo := range snapshot.HavePodsWithRequiredAntiAffinityList() {
+		nodeLabels := nodeInfo.Node().Labels
+		for _, pod := range nodeInfo.PodsWithRequiredAntiAffinity() {
+			for i := range pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+				term, err := newAffinityTerm(pod.Namespace, &pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i])
+				if err != nil {
+					// an invalid term of an existing pod can not be enforced by kube-scheduler either
+					continue
+				}
+				value, ok := nodeLabels[term.topologyKey]
+				if ok && term.matches(requirements.Namespace, namespaceLabels, podLabels) {
+					s.existingBlockedDomains[topologyPair{key: term.topologyKey, value: value}] = true
+				}
+			}
+		}
+	}
+
+	if len(terms) == 0 && len(s.existingBlockedDomains) == 0 {
+		return framework.NewStatus(framework.Skip)
+	}
+	return nil
+}
+
+// getNamespaceLabels returns the labels of the namespace, which are only needed by namespace selectors.
+func (pl *InterPodAffinity) getNamespaceLabels(namespace string) (labels.Set, error) {
+	if pl.namespaceLister == nil || namespace == "" {
+		return nil, nil
+	}
+	ns, err := pl.namespaceLister.Get(namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// a namespace missing from the cache only matches the terms listing it by name
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ns.Labels, nil
+}
+
+func getInterPodAffinityState(state *framework.CycleState) (*interPodAffinityState, error) {
+	data, err := state.Read(preFilterStateKeyInterPodAffinity)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := data.(*interPodAffinityState)
+	if !ok {
+		return nil, fmt.Errorf("%+v convert to interPodAffinityState error", data)
+	}
+	return s, nil
+}
+
+// Filter checks whether the node is in a topology domain the workload can not be placed in.
+func (pl *InterPodAffinity) Filter(_ context.Context, state *framework.CycleState, _ *framework.Requirements, nodeInfo *framework.NodeInfo) *framework.Status {
+	s, err := getInterPodAffinityState(state)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	for key, value := range nodeInfo.Node().Labels {
+		pair := topologyPair{key: key, value: value}
+		if s.existingBlockedDomains[pair] {
+			return framework.NewStatus(framework.Unschedulable, errReasonExistingAntiAffinityRulesNotMatch)
+		}
+		if s.blockedDomains[pair] {
+			return framework.NewStatus(framework.Unschedulable, errReasonAntiAffinityRulesNotMatch)
+		}
+	}
+	return nil
+}
+
+// Estimate bounds the node to one replica when the workload repels itself in a domain of the node.
+func (pl *InterPodAffinity) Estimate(_ context.Context, state *framework.CycleState, _ *framework.Requirements, nodeInfo *framework.NodeInfo) (framework.NodeEstimate, *framework.Status) {
+	s, err := getInterPodAffinityState(state)
+	if err != nil {
+		return framework.NodeEstimate{}, framework.AsStatus(err)
+	}
+	for _, term := range s.selfTerms {
+		if _, ok := nodeInfo.Node().Labels[term.topologyKey]; ok {
+			return framework.NodeEstimate{Replicas: 1}, nil
+		}
+	}
+	return framework.NodeEstimate{Replicas: math.MaxInt64}, nil
+}
+
+// Cap bounds the cluster to one replica per topology domain of every term selecting the workload itself.
+// Nodes without the topology key of a term are not constrained by the term.
+func (pl *InterPodAffinity) Cap(_ context.Context, state *framework.CycleState, _ *framework.Requirements, nodeInfos []*framework.NodeInfo, estimates map[string]int64) (int64, *framework.Status) {
+	s, err := getInterPodAffinityState(state)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+	if len(s.selfTerms) == 0 {
+		return 0, framework.NewStatus(framework.Skip)
+	}
+
+	var limit int64 = math.MaxInt64
+	var reason string
+	for _, term := range s.selfTerms {
+		var replicas int64
+		domains := sets.NewString()
+		for _, nodeInfo := range nodeInfos {
+			node := nodeInfo.Node()
+			if estimates[node.Name] <= 0 {
+				continue
+			}
+			value, ok := node.Labels[term.topologyKey]
+			if !ok {
+				replicas = saturatingAdd(replicas, estimates[node.Name])
+				continue
+			}
+			if !domains.Has(value) {
+				domains.Insert(value)
+				replicas++
+			}
+		}
+		if replicas < limit {
+			limit = replicas
+			reason = fmt.Sprintf("pod anti-affinity allows one replica per %s", term.topologyKey)
+		}
+	}
+	return limit, framework.NewStatus(framework.Success, reason)
+}
+
+// saturatingAdd adds two non-negative integers without overflowing.
+func saturatingAdd(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+const (
+	testHostnameKey = "kubernetes.io/hostname"
+	testZoneKey     = "topology.kubernetes.io/zone"
+)
+
+func newTopologyNode(name, zone string) *corev1.Node {
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   name,
+		Labels: map[string]string{testHostnameKey: name, testZoneKey: zone},
+	}}
+}
+
+func newLabeledPod(namespace string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Labels: podLabels}}
+}
+
+func antiAffinity(topologyKey string, matchLabels map[string]string) *corev1.Affinity {
+	return &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+			LabelSelector: &metav1.LabelSelector{MatchLabels: matchLabels},
+			TopologyKey:   topologyKey,
+		}},
+	}}
+}
+
+// predictionResult is the outcome of running a plugin at all its extension points.
+type predictionResult struct {
+	rejections map[string]string
+	estimates  map[string]int64
+	cap        int64
+	capSkipped bool
+}
+
+// runPlugin runs the plugin against the nodes, each of which could hold nodeReplicas replicas
+// as far as the other plugins are concerned.
+func runPlugin(t *testing.T, pl framework.Plugin, requirements *framework.Requirements, nodeInfos []*framework.NodeInfo, nodeReplicas int64) predictionResult {
+	result := predictionResult{rejections: map[string]string{}, estimates: map[string]int64{}}
+	state := framework.NewCycleState()
+	if status := pl.(framework.PreFilterPlugin).PreFilter(context.TODO(), state, requirements, framework.NewSnapshot(nodeInfos, nil)); !status.IsSuccess() && !status.IsSkip() {
+		t.Fatalf("Unexpected PreFilter status: %v", status.Message())
+	}
+
+	var feasibleNodes []*framework.NodeInfo
+	for _, nodeInfo := range nodeInfos {
+		status := pl.(framework.FilterPlugin).Filter(context.TODO(), state, requirements, nodeInfo)
+		if status.Code() == framework.Error {
+			t.Fatalf("Unexpected Filter status: %v", status.Message())
+		}
+		if !status.IsSuccess() {
+			result.rejections[nodeInfo.Node().Name] = status.Message()
+			continue
+		}
+		feasibleNodes = append(feasibleNodes, nodeInfo)
+		result.estimates[nodeInfo.Node().Name] = nodeReplicas
+		if estimatePlugin, ok := pl.(framework.EstimatePlugin); ok {
+			estimate, status := estimatePlugin.Estimate(context.TODO(), state, requirements, nodeInfo)
+			if !status.IsSuccess() {
+				t.Fatalf("Unexpected Estimate status: %v", status.Message())
+			}
+			if estimate.Replicas < nodeReplicas {
+				result.estimates[nodeInfo.Node().Name] = estimate.Replicas
+			}
+		}
+	}
+
+	replicas, status := pl.(framework.CapPlugin).Cap(context.TODO(), state, requirements, feasibleNodes, result.estimates)
+	if status.Code() == framework.Error {
+		t.Fatalf("Unexpected Cap status: %v", status.Message())
+	}
+	result.cap, result.capSkipped = replicas, status.IsSkip()
+	return result
+}
+
+func TestInterPodAffinity(t *testing.T) {
+	web := map[string]string{"app": "web"}
+	repelling := newLabeledPod("default", map[string]string{"app": "cache"})
+	repelling.Spec.Affinity = antiAffinity(testHostnameKey, web)
+	nodeInfos := []*framework.NodeInfo{
+		framework.NewNodeInfo(newTopologyNode("node-1", "zone-a"), newLabeledPod("default", web)),
+		framework.NewNodeInfo(newTopologyNode("node-2", "zone-a")),
+		framework.NewNodeInfo(newTopologyNode("node-3", "zone-b"), repelling),
+		framework.NewNodeInfo(newTopologyNode("node-4", "zone-b")),
+		framework.NewNodeInfo(newTopologyNode("node-5", "zone-c")),
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		podLabels map[string]string
+		affinity  *corev1.Affinity
+
+		want predictionResult
+	}{
+		{
+			name:      "existing pods repelling the workload",
+			namespace: "default",
+			podLabels: web,
+			want: predictionResult{
+				rejections: map[string]string{"node-3": errReasonExistingAntiAffinityRulesNotMatch},
+				estimates:  map[string]int64{"node-1": 3, "node-2": 3, "node-4": 3, "node-5": 3},
+				capSkipped: true,
+			},
+		},
+		{
+			name:      "one replica per node",
+			namespace: "default",
+			podLabels: web,
+			affinity:  antiAffinity(testHostnameKey, web),
+			want: predictionResult{
+				rejections: map[string]string{
+					"node-1": errReasonAntiAffinityRulesNotMatch,
+					"node-3": errReasonExistingAntiAffinityRulesNotMatch,
+				},
+				estimates: map[string]int64{"node-2": 1, "node-4": 1, "node-5": 1},
+				cap:       3,
+			},
+		},
+		{
+			name:      "one replica per zone",
+			namespace: "default",
+			podLabels: web,
+			affinity:  antiAffinity(testZoneKey, web),
+			want: predictionResult{
+				rejections: map[string]string{
+					"node-1": errReasonAntiAffinityRulesNotMatch,
+					"node-2": errReasonAntiAffinityRulesNotMatch,
+					"node-3": errReasonExistingAntiAffinityRulesNotMatch,
+				},
+				estimates: map[string]int64{"node-4": 1, "node-5": 1},
+				cap:       2,
+			},
+		},
+		{
+			name:      "repelling other workloads",
+			namespace: "default",
+			podLabels: map[string]string{"app": "db"},
+			affinity:  antiAffinity(testZoneKey, web),
+			want: predictionResult{
+				rejections: map[string]string{
+					"node-1": errReasonAntiAffinityRulesNotMatch,
+					"node-2": errReasonAntiAffinityRulesNotMatch,
+				},
+				estimates:  map[string]int64{"node-3": 3, "node-4": 3, "node-5": 3},
+				capSkipped: true,
+			},
+		},
+		{
+			name:      "pods in other namespaces",
+			namespace: "other",
+			podLabels: web,
+			affinity:  antiAffinity(testHostnameKey, web),
+			want: predictionResult{
+				rejections: map[string]string{},
+				estimates:  map[string]int64{"node-1": 1, "node-2": 1, "node-3": 1, "node-4": 1, "node-5": 1},
+				cap:        5,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements := &framework.Requirements{Namespace: tt.namespace, PodLabels: tt.podLabels}
+			requirements.Affinity = tt.affinity
+			got := runPlugin(t, &InterPodAffinity{}, requirements, nodeInfos, 3)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InterPodAffinity = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
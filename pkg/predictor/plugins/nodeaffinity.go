@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+const (
+	// NodeAffinityName is the name of the plugin used in the plugin registry and configurations.
+	NodeAffinityName = "NodeAffinity"
+
+	// nodeFieldName is the only field supported by node selector matchFields.
+	nodeFieldName = "metadata.name"
+
+	errReasonNodeSelectorMismatch = "node(s) didn't match the node selector"
+	errReasonNodeAffinityMismatch = "node(s) didn't match the required node affinity"
+)
+
+// NodeAffinity is a filter plugin that checks the node selector and the required node affinity.
+type NodeAffinity struct{}
+
+var _ framework.FilterPlugin = &NodeAffinity{}
+
+// NewNodeAffinity returns a NodeAffinity plugin.
+func NewNodeAffinity(_ json.RawMessage, _ framework.Handle) (framework.Plugin, error) {
+	return &NodeAffinity{}, nil
+}
+
+// Name returns name of the plugin.
+func (pl *NodeAffinity) Name() string {
+	return NodeAffinityName
+}
+
+// Filter checks whether the node matches the node selector and the required node affinity.
+func (pl *NodeAffinity) Filter(_ context.Context, _ *framework.CycleState, requirements *framework.Requirements, nodeInfo *framework.NodeInfo) *framework.Status {
+	node := nodeInfo.Node()
+	if len(requirements.NodeSelector) != 0 &&
+		!labels.SelectorFromSet(requirements.NodeSelector).Matches(labels.Set(node.Labels)) {
+		return framework.NewStatus(framework.Unschedulable, errReasonNodeSelectorMismatch)
+	}
+	if !matchesRequiredNodeAffinity(node, requirements.Affinity) {
+		return framework.NewStatus(framework.Unschedulable, errReasonNodeAffinityMismatch)
+	}
+	return nil
+}
+
+// matchesRequiredNodeAffinity checks whether the node satisfies the required node affinity.
+func matchesRequiredNodeAffinity(node *corev1.Node, affinity *corev1.Affinity) bool {
+	if affinity == nil || affinity.NodeAffinity == nil ||
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	return matchNodeSelectorTerms(node, affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms)
+}
+
+// matchNodeSelectorTerms checks whether the node matches any of the terms.
+// Same as kube-scheduler, terms are ORed, while requirements inside a term are ANDed,
+// and a term without any requirement or with an invalid requirement matches nothing.
+func matchNodeSelectorTerms(node *corev1.Node, terms []corev1.NodeSelectorTerm) bool {
+	for _, term := range terms {
+		if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+			continue
+		}
+
+		if len(term.MatchExpressions) != 0 {
+			selector, err := nodeSelectorRequirementsAsSelector(term.MatchExpressions)
+			if err != nil || !selector.Matches(labels.Set(node.Labels)) {
+				continue
+			}
+		}
+
+		if len(term.MatchFields) != 0 {
+			matched, err := matchNodeFields(node, term.MatchFields)
+			if err != nil || !matched {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// nodeSelectorRequirementsAsSelector converts node selector requirements to a label selector.
+func nodeSelectorRequirementsAsSelector(requirements []corev1.NodeSelectorRequirement) (labels.Selector, error) {
+	selector := labels.NewSelector()
+	for _, expr := range requirements {
+		var op selection.Operator
+		switch expr.Operator {
+		case corev1.NodeSelectorOpIn:
+			op = selection.In
+		case corev1.NodeSelectorOpNotIn:
+			op = selection.NotIn
+		case corev1.NodeSelectorOpExists:
+			op = selection.Exists
+		case corev1.NodeSelectorOpDoesNotExist:
+			op = selection.DoesNotExist
+		case corev1.NodeSelectorOpGt:
+			op = selection.GreaterThan
+		case corev1.NodeSelectorOpLt:
+			op = selection.LessThan
+		default:
+			return nil, fmt.Errorf("%q is not a valid node selector operator", expr.Operator)
+		}
+		r, err := labels.NewRequirement(expr.Key, op, expr.Values)
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*r)
+	}
+	return selector, nil
+}
+
+// matchNodeFields checks whether the node matches all the field requirements.
+func matchNodeFields(node *corev1.Node, requirements []corev1.NodeSelectorRequirement) (bool, error) {
+	for _, expr := range requirements {
+		if expr.Key != nodeFieldName {
+			return false, fmt.Errorf("%q is not a valid field selector key", expr.Key)
+		}
+		if len(expr.Values) != 1 {
+			return false, fmt.Errorf("unexpected number of values (%d) for node field selector operator %q",
+				len(expr.Values), expr.Operator)
+		}
+
+		switch expr.Operator {
+		case corev1.NodeSelectorOpIn:
+			if node.Name != expr.Values[0] {
+				return false, nil
+			}
+		case corev1.NodeSelectorOpNotIn:
+			if node.Name == expr.Values[0] {
+				return false, nil
+			}
+		default:
+			return false, fmt.Errorf("%q is not a valid node field selector operator", expr.Operator)
+		}
+	}
+	return true, nil
+}
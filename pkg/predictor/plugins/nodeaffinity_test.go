@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMatchNodeSelectorTerms(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-1",
+			Labels: map[string]string{
+				"kubernetes.io/os": "linux",
+				"disktype":         "ssd",
+				"gpu-count":        "4",
+			},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		terms []corev1.NodeSelectorTerm
+		want  bool
+	}{
+		{
+			name:  "no terms",
+			terms: []corev1.NodeSelectorTerm{},
+			want:  false,
+		},
+		{
+			name:  "empty term",
+			terms: []corev1.NodeSelectorTerm{{}},
+			want:  false,
+		},
+		{
+			name: "in and exists",
+			terms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "kubernetes.io/os", Operator: corev1.NodeSelectorOpIn, Values: []string{"linux", "windows"}},
+					{Key: "disktype", Operator: corev1.NodeSelectorOpExists},
+				},
+			}},
+			want: true,
+		},
+		{
+			name: "requirements in a term are ANDed",
+			terms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "kubernetes.io/os", Operator: corev1.NodeSelectorOpIn, Values: []string{"linux"}},
+					{Key: "disktype", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"ssd"}},
+				},
+			}},
+			want: false,
+		},
+		{
+			name: "terms are ORed",
+			terms: []corev1.NodeSelectorTerm{
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "zone", Operator: corev1.NodeSelectorOpExists},
+					},
+				},
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "zone", Operator: corev1.NodeSelectorOpDoesNotExist},
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "gt and lt",
+			terms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "gpu-count", Operator: corev1.NodeSelectorOpGt, Values: []string{"2"}},
+					{Key: "gpu-count", Operator: corev1.NodeSelectorOpLt, Values: []string{"8"}},
+				},
+			}},
+			want: true,
+		},
+		{
+			name: "gt on a non-integer label",
+			terms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "disktype", Operator: corev1.NodeSelectorOpGt, Values: []string{"2"}},
+				},
+			}},
+			want: false,
+		},
+		{
+			name: "invalid term does not match while others still do",
+			terms: []corev1.NodeSelectorTerm{
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "gpu-count", Operator: corev1.NodeSelectorOpGt, Values: []string{"two"}},
+					},
+				},
+				{
+					MatchFields: []corev1.NodeSelectorRequirement{
+						{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node-1"}},
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "match fields not in",
+			terms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "kubernetes.io/os", Operator: corev1.NodeSelectorOpIn, Values: []string{"linux"}},
+				},
+				MatchFields: []corev1.NodeSelectorRequirement{
+					{Key: "metadata.name", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"node-1"}},
+				},
+			}},
+			want: false,
+		},
+		{
+			name: "match fields with unsupported key",
+			terms: []corev1.NodeSelectorTerm{{
+				MatchFields: []corev1.NodeSelectorRequirement{
+					{Key: "metadata.namespace", Operator: corev1.NodeSelectorOpIn, Values: []string{"node-1"}},
+				},
+			}},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchNodeSelectorTerms(node, tt.terms); got != tt.want {
+				t.Errorf("matchNodeSelectorTerms() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	coordinationlisters "k8s.io/client-go/listers/coordination/v1"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+// NodeHealthName is the name of the plugin used in the plugin registry and configurations.
+const NodeHealthName = "NodeHealth"
+
+const (
+	errReasonNotReady   = "node(s) were not ready"
+	errReasonNoLease    = "node(s) had no lease"
+	errReasonStaleLease = "node(s) had a stale lease"
+)
+
+// conditionReasons are the reasons of the node conditions that exclude a node when they are True.
+var conditionReasons = map[corev1.NodeConditionType]string{
+	corev1.NodeMemoryPressure: "node(s) had memory pressure",
+	corev1.NodeDiskPressure:   "node(s) had disk pressure",
+	corev1.NodePIDPressure:    "node(s) had pid pressure",
+}
+
+// NodeHealthArgs holds arguments used to configure the NodeHealth plugin.
+type NodeHealthArgs struct {
+	// TolerateConditions are the node conditions that do not exclude a node, e.g. "PIDPressure".
+	// "Ready" tolerates nodes that are not ready.
+	TolerateConditions []corev1.NodeConditionType `json:"tolerateConditions,omitempty"`
+	// LeaseMaxAgeSeconds excludes the nodes whose Lease has not been renewed within the period.
+	// The check is disabled when it is 0.
+	LeaseMaxAgeSeconds int64 `json:"leaseMaxAgeSeconds,omitempty"`
+}
+
+// NodeHealth is a filter plugin that excludes not ready and pressured nodes, as well as the nodes
+// whose kubelet has stopped renewing its Lease. Cordoned nodes are excluded by TaintToleration.
+type NodeHealth struct {
+	tolerated   map[corev1.NodeConditionType]bool
+	leaseMaxAge time.Duration
+	// leaseLister lists the Leases of the kube-node-lease namespace.
+	leaseLister coordinationlisters.LeaseNamespaceLister
+	now         func() time.Time
+}
+
+var _ framework.FilterPlugin = &NodeHealth{}
+
+// NewNodeHealth returns a NodeHealth plugin.
+func NewNodeHealth(rawArgs json.RawMessage, handle framework.Handle) (framework.Plugin, error) {
+	var args NodeHealthArgs
+	if len(rawArgs) != 0 {
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+	}
+	if args.LeaseMaxAgeSeconds < 0 {
+		return nil, fmt.Errorf("leaseMaxAgeSeconds must not be negative")
+	}
+
+	pl := &NodeHealth{
+		tolerated:   make(map[corev1.NodeConditionType]bool),
+		leaseMaxAge: time.Duration(args.LeaseMaxAgeSeconds) * time.Second,
+		now:         time.Now,
+	}
+	for _, condition := range args.TolerateConditions {
+		if _, ok := conditionReasons[condition]; !ok && condition != corev1.NodeReady {
+			return nil, fmt.Errorf("unknown node condition %q to tolerate", condition)
+		}
+		pl.tolerated[condition] = true
+	}
+	if pl.leaseMaxAge > 0 {
+		pl.leaseLister = handle.SharedInformerFactory().Coordination().V1().Leases().Lister().Leases(corev1.NamespaceNodeLease)
+	}
+	return pl, nil
+}
+
+// Name returns name of the plugin.
+func (pl *NodeHealth) Name() string {
+	return NodeHealthName
+}
+
+// Filter checks whether the node is schedulable, ready, without pressure and renewing its Lease.
+func (pl *NodeHealth) Filter(_ context.Context, _ *framework.CycleState, _ *framework.Requirements, nodeInfo *framework.NodeInfo) *framework.Status {
+	node := nodeInfo.Node()
+	for _, condition := range node.Status.Conditions {
+		if pl.tolerated[condition.Type] {
+			continue
+		}
+		if condition.Type == corev1.NodeReady && condition.Status != corev1.ConditionTrue {
+			return framework.NewStatus(framework.Unschedulable, errReasonNotReady)
+		}
+		if reason, ok := conditionReasons[condition.Type]; ok && condition.Status == corev1.ConditionTrue {
+			return framework.NewStatus(framework.Unschedulable, reason)
+		}
+	}
+
+	if pl.leaseLister == nil {
+		return nil
+	}
+	lease, err := pl.leaseLister.Get(node.Name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return framework.NewStatus(framework.Unschedulable, errReasonNoLease)
+		}
+		return framework.AsStatus(err)
+	}
+	if lease.Spec.RenewTime == nil || pl.now().Sub(lease.Spec.RenewTime.Time) > pl.leaseMaxAge {
+		return framework.NewStatus(framework.Unschedulable, errReasonStaleLease)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationlisters "k8s.io/client-go/listers/coordination/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+func TestNodeHealth(t *testing.T) {
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for name, renewed := range map[string]time.Duration{"fresh": 10 * time.Second, "stale": 2 * time.Minute} {
+		renewTime := metav1.NewMicroTime(now.Add(-renewed))
+		if err := indexer.Add(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: corev1.NamespaceNodeLease, Name: name},
+			Spec:       coordinationv1.LeaseSpec{RenewTime: &renewTime},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ready := corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionTrue}
+
+	tests := []struct {
+		name       string
+		node       *corev1.Node
+		args       NodeHealthArgs
+		checkLease bool
+		wantReason string
+	}{
+		{
+			name: "healthy node",
+			node: &corev1.Node{Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{ready}}},
+		},
+		{
+			name: "cordoned node is left to TaintToleration",
+			node: &corev1.Node{
+				Spec:   corev1.NodeSpec{Unschedulable: true},
+				Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{ready}},
+			},
+		},
+		{
+			name: "not ready node",
+			node: &corev1.Node{Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionUnknown},
+			}}},
+			wantReason: errReasonNotReady,
+		},
+		{
+			name: "pid pressure",
+			node: &corev1.Node{Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				ready, {Type: corev1.NodePIDPressure, Status: corev1.ConditionTrue},
+			}}},
+			wantReason: "node(s) had pid pressure",
+		},
+		{
+			name: "pid pressure tolerated",
+			node: &corev1.Node{Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				ready, {Type: corev1.NodePIDPressure, Status: corev1.ConditionTrue},
+			}}},
+			args: NodeHealthArgs{TolerateConditions: []corev1.NodeConditionType{corev1.NodePIDPressure}},
+		},
+		{
+			name:       "fresh lease",
+			node:       &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "fresh"}},
+			checkLease: true,
+		},
+		{
+			name:       "stale lease",
+			node:       &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "stale"}},
+			checkLease: true,
+			wantReason: errReasonStaleLease,
+		},
+		{
+			name:       "missing lease",
+			node:       &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "missing"}},
+			checkLease: true,
+			wantReason: errReasonNoLease,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl := &NodeHealth{tolerated: make(map[corev1.NodeConditionType]bool), now: func() time.Time { return now }}
+			for _, condition := range tt.args.TolerateConditions {
+				pl.tolerated[condition] = true
+			}
+			if tt.checkLease {
+				pl.leaseMaxAge = 40 * time.Second
+				pl.leaseLister = coordinationlisters.NewLeaseLister(indexer).Leases(corev1.NamespaceNodeLease)
+			}
+
+			status := pl.Filter(context.TODO(), framework.NewCycleState(), &framework.Requirements{}, framework.NewNodeInfo(tt.node))
+			if tt.wantReason == "" {
+				if !status.IsSuccess() {
+					t.Errorf("Filter() = %v, want success", status.Message())
+				}
+				return
+			}
+			if status.Code() != framework.Unschedulable || status.Message() != tt.wantReason {
+				t.Errorf("Filter() = %v %q, want Unschedulable %q", status.Code(), status.Message(), tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+// NodeResourcesFitName is the name of the plugin used in the plugin registry and configurations.
+const NodeResourcesFitName = "NodeResourcesFit"
+
+// BestEffortPolicy decides how many replicas of a BestEffort workload, which requests neither cpu
+// nor memory, a node could hold.
+type BestEffortPolicy string
+
+const (
+	// BestEffortPodSlots bounds the replicas only by the free pod slots of the node.
+	BestEffortPodSlots BestEffortPolicy = "PodSlots"
+	// BestEffortLimit bounds the replicas by both the free pod slots and BestEffortLimit.
+	BestEffortLimit BestEffortPolicy = "Limit"
+	// BestEffortDeny does not accept any replica of BestEffort workloads.
+	BestEffortDeny BestEffortPolicy = "Deny"
+)
+
+// limitedByBestEffort is reported when the replicas are bounded by the BestEffort policy.
+const limitedByBestEffort = "BestEffort"
+
+// NodeResourcesFitArgs holds arguments used to configure the NodeResourcesFit plugin.
+type NodeResourcesFitArgs struct {
+	// BestEffortPolicy is one of PodSlots, Limit and Deny. Defaults to PodSlots.
+	BestEffortPolicy BestEffortPolicy `json:"bestEffortPolicy,omitempty"`
+	// BestEffortLimit is the max replicas of a BestEffort workload per node under the Limit policy.
+	BestEffortLimit int64 `json:"bestEffortLimit,omitempty"`
+}
+
+// NodeResourcesFit is an estimate plugin that divides the free resources of a node by the requests,
+// and bounds the replicas by the free pod slots of the node.
+type NodeResourcesFit struct {
+	bestEffortPolicy BestEffortPolicy
+	bestEffortLimit  int64
+}
+
+var _ framework.EstimatePlugin = &NodeResourcesFit{}
+
+// NewNodeResourcesFit returns a NodeResourcesFit plugin.
+func NewNodeResourcesFit(rawArgs json.RawMessage, _ framework.Handle) (framework.Plugin, error) {
+	args := NodeResourcesFitArgs{BestEffortPolicy: BestEffortPodSlots}
+	if len(rawArgs) != 0 {
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+	}
+	switch args.BestEffortPolicy {
+	case "":
+		args.BestEffortPolicy = BestEffortPodSlots
+	case BestEffortPodSlots, BestEffortDeny:
+	case BestEffortLimit:
+		if args.BestEffortLimit <= 0 {
+			return nil, fmt.Errorf("bestEffortLimit must be positive with the %s policy", BestEffortLimit)
+		}
+	default:
+		return nil, fmt.Errorf("unknown bestEffortPolicy %q, must be %s, %s or %s",
+			args.BestEffortPolicy, BestEffortPodSlots, BestEffortLimit, BestEffortDeny)
+	}
+
+	return &NodeResourcesFit{
+		bestEffortPolicy: args.BestEffortPolicy,
+		bestEffortLimit:  args.BestEffortLimit,
+	}, nil
+}
+
+// Name returns name of the plugin.
+func (pl *NodeResourcesFit) Name() string {
+	return NodeResourcesFitName
+}
+
+// Estimate returns the replicas that fit in both the free pod slots and the free resources of the node.
+func (pl *NodeResourcesFit) Estimate(_ context.Context, _ *framework.CycleState, requirements *framework.Requirements, nodeInfo *framework.NodeInfo) (framework.NodeEstimate, *framework.Status) {
+	n := nodeInfo.Node()
+	// same as kube-scheduler, a node not reporting allocatable pods could not hold any pod
+	allocatablePods := n.Status.Allocatable[corev1.ResourcePods]
+	slots := allocatablePods.Value() - nodeInfo.NumPods()
+	if slots < 0 {
+		slots = 0
+	}
+	estimate := framework.NodeEstimate{Replicas: slots, LimitedBy: string(corev1.ResourcePods)}
+	if estimate.Replicas == 0 {
+		return estimate, nil
+	}
+
+	// BestEffort workloads may still request extended resources, which bound them as well
+	if isBestEffort(requirements.Resources) {
+		switch pl.bestEffortPolicy {
+		case BestEffortDeny:
+			return framework.NodeEstimate{Replicas: 0, LimitedBy: limitedByBestEffort}, nil
+		case BestEffortLimit:
+			if pl.bestEffortLimit < estimate.Replicas {
+				estimate = framework.NodeEstimate{Replicas: pl.bestEffortLimit, LimitedBy: limitedByBestEffort}
+			}
+		}
+	}
+
+	for resourceName, resource := range requirements.Resources.Requests {
+		// pod slots are bounded by the count of pods instead
+		if resource.IsZero() || resourceName == corev1.ResourcePods {
+			continue
+		}
+		// free resource is allocatable (capacity minus system reserved) minus requests of running pods
+		free := nodeInfo.Free[resourceName]
+		if resource.Cmp(free) > 0 {
+			if klogV := klog.V(5); klogV.Enabled() {
+				klogV.Infof("node %s resource %s(%s) is not enough for request %s",
+					n.Name, resourceName, free.String(), resource.String())
+			}
+			return framework.NodeEstimate{Replicas: 0, LimitedBy: string(resourceName)}, nil
+		}
+		multiple := framework.DivideQuantity(free, resource)
+		if klogV := klog.V(5); klogV.Enabled() {
+			klogV.Infof("resource %s: node(%s) has %s free, pod need %s, replicas is %d.",
+				resourceName, n.Name, free.String(), resource.String(), multiple)
+		}
+		if estimate.Replicas > multiple {
+			estimate.Replicas = multiple
+			estimate.LimitedBy = string(resourceName)
+		}
+	}
+	return estimate, nil
+}
+
+// isBestEffort checks whether the resources request and limit neither cpu nor memory.
+func isBestEffort(resources corev1.ResourceRequirements) bool {
+	for _, resourceName := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if quantity, ok := resources.Requests[resourceName]; ok && !quantity.IsZero() {
+			return false
+		}
+		if quantity, ok := resources.Limits[resourceName]; ok && !quantity.IsZero() {
+			return false
+		}
+	}
+	return true
+}
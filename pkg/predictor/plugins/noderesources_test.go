@@ -0,0 +1,193 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+func newPod(name string, requests corev1.ResourceList) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{Requests: requests}}},
+		},
+	}
+}
+
+func TestNodeResourcesFit(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("8"),
+				corev1.ResourceMemory: resource.MustParse("16Gi"),
+				corev1.ResourcePods:   resource.MustParse("10"),
+			},
+		},
+	}
+	pods := []*corev1.Pod{
+		newPod("pod-1", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")}),
+		newPod("pod-2", nil),
+		newPod("pod-3", nil),
+	}
+
+	tests := []struct {
+		name     string
+		args     *NodeResourcesFitArgs
+		node     *corev1.Node
+		requests corev1.ResourceList
+
+		want framework.NodeEstimate
+	}{
+		{
+			name:     "bounded by cpu",
+			requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+			want:     framework.NodeEstimate{Replicas: 3, LimitedBy: "cpu"},
+		},
+		{
+			name:     "bounded by free pod slots",
+			requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+			want:     framework.NodeEstimate{Replicas: 7, LimitedBy: "pods"},
+		},
+		{
+			name: "best effort bounded by free pod slots",
+			want: framework.NodeEstimate{Replicas: 7, LimitedBy: "pods"},
+		},
+		{
+			name: "best effort with the limit policy",
+			args: &NodeResourcesFitArgs{BestEffortPolicy: BestEffortLimit, BestEffortLimit: 2},
+			want: framework.NodeEstimate{Replicas: 2, LimitedBy: limitedByBestEffort},
+		},
+		{
+			name: "best effort with the deny policy",
+			args: &NodeResourcesFitArgs{BestEffortPolicy: BestEffortDeny},
+			want: framework.NodeEstimate{Replicas: 0, LimitedBy: limitedByBestEffort},
+		},
+		{
+			name:     "no allocatable pods",
+			node:     &corev1.Node{Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")}}},
+			requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+			want:     framework.NodeEstimate{Replicas: 0, LimitedBy: "pods"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rawArgs json.RawMessage
+			if tt.args != nil {
+				rawArgs, _ = json.Marshal(tt.args)
+			}
+			pl, err := NewNodeResourcesFit(rawArgs, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			n := node
+			if tt.node != nil {
+				n = tt.node
+			}
+			requirements := &framework.Requirements{}
+			requirements.Resources.Requests = tt.requests
+
+			got, status := pl.(*NodeResourcesFit).Estimate(context.TODO(), framework.NewCycleState(), requirements, framework.NewNodeInfo(n, pods...))
+			if !status.IsSuccess() {
+				t.Fatalf("Unexpected status: %v", status.Message())
+			}
+			if got != tt.want {
+				t.Errorf("Estimate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeResourcesFitPrecision(t *testing.T) {
+	tests := []struct {
+		name        string
+		allocatable corev1.ResourceList
+		requests    corev1.ResourceList
+		// wantLegacy is the estimate when quantities were divided by their Value(), which rounds up to integers.
+		wantLegacy int64
+		want       int64
+	}{
+		{
+			name:        "sub-core cpu request",
+			allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+			requests:    corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			wantLegacy:  4,
+			want:        40,
+		},
+		{
+			name:        "fractional cpu request",
+			allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("3500m")},
+			requests:    corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1500m")},
+			wantLegacy:  2,
+			want:        2,
+		},
+		{
+			name:        "sub-core free cpu",
+			allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2500m")},
+			requests:    corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+			wantLegacy:  3,
+			want:        10,
+		},
+		{
+			name:        "fractional memory",
+			allocatable: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1.5Gi")},
+			requests:    corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("0.5Gi")},
+			wantLegacy:  3,
+			want:        3,
+		},
+		{
+			name:        "fractional extended resource",
+			allocatable: corev1.ResourceList{"example.com/vgpu": resource.MustParse("2")},
+			requests:    corev1.ResourceList{"example.com/vgpu": resource.MustParse("500m")},
+			wantLegacy:  2,
+			want:        4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocatable := tt.allocatable.DeepCopy()
+			allocatable[corev1.ResourcePods] = resource.MustParse("110")
+			node := &corev1.Node{Status: corev1.NodeStatus{Allocatable: allocatable}}
+			requirements := &framework.Requirements{}
+			requirements.Resources.Requests = tt.requests
+
+			pl := &NodeResourcesFit{bestEffortPolicy: BestEffortPodSlots}
+			got, status := pl.Estimate(context.TODO(), framework.NewCycleState(), requirements, framework.NewNodeInfo(node))
+			if !status.IsSuccess() {
+				t.Fatalf("Unexpected status: %v", status.Message())
+			}
+			if got.Replicas != tt.want {
+				t.Errorf("Estimate() = %d, want %d", got.Replicas, tt.want)
+			}
+
+			for name, request := range tt.requests {
+				free := tt.allocatable[name]
+				if legacy := free.Value() / request.Value(); legacy != tt.wantLegacy {
+					t.Errorf("legacy estimate = %d, want %d", legacy, tt.wantLegacy)
+				}
+			}
+		})
+	}
+}
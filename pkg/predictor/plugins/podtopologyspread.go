@@ -0,0 +1,286 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+const (
+	// PodTopologySpreadName is the name of the plugin used in the plugin registry and configurations.
+	PodTopologySpreadName = "PodTopologySpread"
+
+	preFilterStateKeyPodTopologySpread = "PreFilter" + PodTopologySpreadName
+
+	errReasonConstraintsNotMatch = "node(s) didn't match pod topology spread constraints"
+	errReasonNodeLabelNotMatch   = errReasonConstraintsNotMatch + " (missing required label)"
+)
+
+// PodTopologySpread applies the topology spread constraints of the workload with the DoNotSchedule action.
+//
+// Same as kube-scheduler, the domains of a constraint are those of the nodes matching the node
+// selector and the required node affinity of the workload, and the skew is computed with the existing
+// pods selected by the constraint in the namespace of the workload. The replicas of a domain are bounded
+// so that the domain does not exceed the least loaded domain, once it is filled up, by more than maxSkew.
+type PodTopologySpread struct{}
+
+var _ framework.PreFilterPlugin = &PodTopologySpread{}
+var _ framework.FilterPlugin = &PodTopologySpread{}
+var _ framework.CapPlugin = &PodTopologySpread{}
+
+// NewPodTopologySpread returns a PodTopologySpread plugin.
+func NewPodTopologySpread(_ json.RawMessage, _ framework.Handle) (framework.Plugin, error) {
+	return &PodTopologySpread{}, nil
+}
+
+// Name returns name of the plugin.
+func (pl *PodTopologySpread) Name() string {
+	return PodTopologySpreadName
+}
+
+// topologySpreadConstraint is a parsed DoNotSchedule topology spread constraint.
+type topologySpreadConstraint struct {
+	maxSkew     int64
+	topologyKey string
+	selector    labels.Selector
+	// selfMatching is whether the constraint selects the workload itself, so that every
+	// replica placed in a domain increases the skew.
+	selfMatching bool
+	// matched caches whether the selector matches the pod groups, keyed by PodGroupKey.
+	matched map[string]bool
+	// existing is the count of existing matching pods in every domain.
+	existing map[string]int64
+	// minExisting is the count of existing matching pods in the least loaded domain.
+	minExisting int64
+}
+
+// podTopologySpreadState is computed at PreFilter and read back at the other extension points.
+type podTopologySpreadState struct {
+	constraints []*topologySpreadConstraint
+}
+
+// PreFilter counts the existing matching pods in every domain of the constraints.
+func (pl *PodTopologySpread) PreFilter(_ context.Context, state *framework.CycleState, requirements *framework.Requirements, snapshot *framework.Snapshot) *framework.Status {
+	s := &podTopologySpreadState{}
+	state.Write(preFilterStateKeyPodTopologySpread, s)
+
+	for _, constraint := range requirements.TopologySpreadConstraints {
+		if constraint.WhenUnsatisfiable != corev1.DoNotSchedule {
+			continue
+		}
+		if constraint.MaxSkew <= 0 {
+			return framework.AsStatus(fmt.Errorf("maxSkew of topology key %q must be positive", constraint.TopologyKey))
+		}
+		selector, err := metav1.LabelSelectorAsSelector(constraint.LabelSelector)
+		if err != nil {
+			return framework.AsStatus(fmt.Errorf("invalid label selector of topology key %q: %v", constraint.TopologyKey, err))
+		}
+		s.constraints = append(s.constraints, &topologySpreadConstraint{
+			maxSkew:      int64(constraint.MaxSkew),
+			topologyKey:  constraint.TopologyKey,
+			selector:     selector,
+			selfMatching: selector.Matches(labels.Set(requirements.PodLabels)),
+			matched:      make(map[string]bool),
+			existing:     make(map[string]int64),
+		})
+	}
+	if len(s.constraints) == 0 {
+		return framework.NewStatus(framework.Skip)
+	}
+
+	if pl.allNodesEligible(requirements, s, snapshot) {
+		// the pods of every domain are counted by the snapshot ahead
+		for _, c := range s.constraints {
+			for value, groups := range snapshot.TopologyDomains(c.topologyKey).PodGroups {
+				c.existing[value] += c.countMatching(requirements.Namespace, groups)
+			}
+		}
+	} else {
+		nodeInfos := snapshot.List()
+		// the nodes matching the node selector are looked up by any of its labels
+		for key, value := range requirements.NodeSelector {
+			nodeInfos = snapshot.NodesWithLabel(key, value)
+			break
+		}
+		for _, nodeInfo := range nodeInfos {
+			node := nodeInfo.Node()
+			if !pl.isEligibleNode(requirements, s, node) {
+				continue
+			}
+			for _, c := range s.constraints {
+				// make sure empty domains are counted as well
+				value := node.Labels[c.topologyKey]
+				c.existing[value] += c.countMatching(requirements.Namespace, nodeInfo.PodGroups())
+			}
+		}
+	}
+	for _, c := range s.constraints {
+		c.minExisting = math.MaxInt64
+		for _, count := range c.existing {
+			if count < c.minExisting {
+				c.minExisting = count
+			}
+		}
+	}
+	return nil
+}
+
+// allNodesEligible checks whether the domains of all the nodes count, i.e. the workload has neither a node
+// selector nor required node affinity, and every node has all the topology keys.
+func (pl *PodTopologySpread) allNodesEligible(requirements *framework.Requirements, s *podTopologySpreadState, snapshot *framework.Snapshot) bool {
+	if len(requirements.NodeSelector) != 0 {
+		return false
+	}
+	if affinity := requirements.Affinity; affinity != nil && affinity.NodeAffinity != nil &&
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		return false
+	}
+	for _, c := range s.constraints {
+		if snapshot.TopologyDomains(c.topologyKey).Nodes != snapshot.NumNodes() {
+			return false
+		}
+	}
+	return true
+}
+
+// countMatching counts the pods of the groups in the namespace selected by the constraint.
+// The selector is matched once per group across all the nodes.
+func (c *topologySpreadConstraint) countMatching(namespace string, groups map[string]framework.PodGroup) int64 {
+	var count int64
+	for key, group := range groups {
+		if group.Namespace != namespace {
+			continue
+		}
+		matched, ok := c.matched[key]
+		if !ok {
+			matched = c.selector.Matches(labels.Set(group.Labels))
+			c.matched[key] = matched
+		}
+		if matched {
+			count += group.Count
+		}
+	}
+	return count
+}
+
+// isEligibleNode checks whether the domains of the node count, i.e. the node matches the node
+// selector and the required node affinity of the workload, and has all the topology keys.
+func (pl *PodTopologySpread) isEligibleNode(requirements *framework.Requirements, s *podTopologySpreadState, node *corev1.Node) bool {
+	if len(requirements.NodeSelector) != 0 &&
+		!labels.SelectorFromSet(requirements.NodeSelector).Matches(labels.Set(node.Labels)) {
+		return false
+	}
+	if !matchesRequiredNodeAffinity(node, requirements.Affinity) {
+		return false
+	}
+	for _, c := range s.constraints {
+		if _, ok := node.Labels[c.topologyKey]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func getPodTopologySpreadState(state *framework.CycleState) (*podTopologySpreadState, error) {
+	data, err := state.Read(preFilterStateKeyPodTopologySpread)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := data.(*podTopologySpreadState)
+	if !ok {
+		return nil, fmt.Errorf("%+v convert to podTopologySpreadState error", data)
+	}
+	return s, nil
+}
+
+// Filter checks whether the node has the topology keys of all the constraints, and whether its domains
+// are within maxSkew of the least loaded domains for the constraints not selecting the workload.
+func (pl *PodTopologySpread) Filter(_ context.Context, state *framework.CycleState, _ *framework.Requirements, nodeInfo *framework.NodeInfo) *framework.Status {
+	s, err := getPodTopologySpreadState(state)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	node := nodeInfo.Node()
+	for _, c := range s.constraints {
+		value, ok := node.Labels[c.topologyKey]
+		if !ok {
+			return framework.NewStatus(framework.Unschedulable, errReasonNodeLabelNotMatch)
+		}
+		// replicas selected by the constraint raise the least loaded domain as well, which is left to Cap
+		if !c.selfMatching && c.existing[value]-c.minExisting > c.maxSkew {
+			return framework.NewStatus(framework.Unschedulable, errReasonConstraintsNotMatch)
+		}
+	}
+	return nil
+}
+
+// Cap bounds the replicas of every domain of the constraints selecting the workload itself.
+// When all domains are filled up to their estimates, the least loaded domain holds
+// min(existing + estimates) pods, and no domain could hold more than that plus maxSkew.
+func (pl *PodTopologySpread) Cap(_ context.Context, state *framework.CycleState, _ *framework.Requirements, nodeInfos []*framework.NodeInfo, estimates map[string]int64) (int64, *framework.Status) {
+	s, err := getPodTopologySpreadState(state)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+
+	var limit int64 = math.MaxInt64
+	var reason string
+	for _, c := range s.constraints {
+		if !c.selfMatching {
+			continue
+		}
+		capacity := make(map[string]int64, len(c.existing))
+		for _, nodeInfo := range nodeInfos {
+			node := nodeInfo.Node()
+			value := node.Labels[c.topologyKey]
+			capacity[value] = saturatingAdd(capacity[value], estimates[node.Name])
+		}
+
+		var minFilled int64 = math.MaxInt64
+		for value, existing := range c.existing {
+			if filled := saturatingAdd(existing, capacity[value]); filled < minFilled {
+				minFilled = filled
+			}
+		}
+		bound := saturatingAdd(minFilled, c.maxSkew)
+
+		var replicas int64
+		for value, existing := range c.existing {
+			filled := saturatingAdd(existing, capacity[value])
+			if filled > bound {
+				filled = bound
+			}
+			if filled > existing {
+				replicas = saturatingAdd(replicas, filled-existing)
+			}
+		}
+		if replicas < limit {
+			limit = replicas
+			reason = fmt.Sprintf("topology spread constraint on %s allows a max skew of %d", c.topologyKey, c.maxSkew)
+		}
+	}
+	if limit == math.MaxInt64 {
+		return 0, framework.NewStatus(framework.Skip)
+	}
+	return limit, framework.NewStatus(framework.Success, reason)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+func TestPodTopologySpread(t *testing.T) {
+	web := map[string]string{"app": "web"}
+	unlabeled := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "node-6",
+		Labels: map[string]string{testHostnameKey: "node-6"},
+	}}
+	nodeInfos := []*framework.NodeInfo{
+		framework.NewNodeInfo(newTopologyNode("node-1", "zone-a"), newLabeledPod("default", web), newLabeledPod("default", web)),
+		framework.NewNodeInfo(newTopologyNode("node-2", "zone-a"), newLabeledPod("other", web)),
+		framework.NewNodeInfo(newTopologyNode("node-3", "zone-b")),
+		framework.NewNodeInfo(newTopologyNode("node-4", "zone-b")),
+		framework.NewNodeInfo(newTopologyNode("node-5", "zone-c")),
+		framework.NewNodeInfo(unlabeled),
+	}
+	spread := func(topologyKey string, action corev1.UnsatisfiableConstraintAction) []corev1.TopologySpreadConstraint {
+		return []corev1.TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       topologyKey,
+			WhenUnsatisfiable: action,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: web},
+		}}
+	}
+	allFeasible := map[string]int64{"node-1": 3, "node-2": 3, "node-3": 3, "node-4": 3, "node-5": 3, "node-6": 3}
+	labeledFeasible := map[string]int64{"node-1": 3, "node-2": 3, "node-3": 3, "node-4": 3, "node-5": 3}
+
+	tests := []struct {
+		name         string
+		podLabels    map[string]string
+		nodeSelector map[string]string
+		constraints  []corev1.TopologySpreadConstraint
+
+		want predictionResult
+	}{
+		{
+			name:        "no constraint",
+			podLabels:   web,
+			constraints: spread(testZoneKey, corev1.ScheduleAnyway),
+			want:        predictionResult{rejections: map[string]string{}, estimates: allFeasible, capSkipped: true},
+		},
+		{
+			name:        "spread across zones",
+			podLabels:   web,
+			constraints: spread(testZoneKey, corev1.DoNotSchedule),
+			// zone-c fills up at 3 pods, so zone-a takes 2 more and zone-b takes 4
+			want: predictionResult{
+				rejections: map[string]string{"node-6": errReasonNodeLabelNotMatch},
+				estimates:  labeledFeasible,
+				cap:        9,
+			},
+		},
+		{
+			name:        "spread across nodes",
+			podLabels:   web,
+			constraints: spread(testHostnameKey, corev1.DoNotSchedule),
+			// every node fills up at 3 pods, except node-1 that takes 2 more
+			want: predictionResult{rejections: map[string]string{}, estimates: allFeasible, cap: 17},
+		},
+		{
+			name:         "domains of the selected nodes only",
+			podLabels:    web,
+			nodeSelector: map[string]string{testZoneKey: "zone-a"},
+			constraints:  spread(testZoneKey, corev1.DoNotSchedule),
+			want: predictionResult{
+				rejections: map[string]string{"node-6": errReasonNodeLabelNotMatch},
+				estimates:  labeledFeasible,
+				cap:        6,
+			},
+		},
+		{
+			name:        "constraints selecting other workloads",
+			podLabels:   map[string]string{"app": "db"},
+			constraints: spread(testZoneKey, corev1.DoNotSchedule),
+			want: predictionResult{
+				rejections: map[string]string{
+					"node-1": errReasonConstraintsNotMatch,
+					"node-2": errReasonConstraintsNotMatch,
+					"node-6": errReasonNodeLabelNotMatch,
+				},
+				estimates:  map[string]int64{"node-3": 3, "node-4": 3, "node-5": 3},
+				capSkipped: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements := &framework.Requirements{
+				Namespace:                 "default",
+				PodLabels:                 tt.podLabels,
+				TopologySpreadConstraints: tt.constraints,
+			}
+			requirements.NodeSelector = tt.nodeSelector
+			got := runPlugin(t, &PodTopologySpread{}, requirements, nodeInfos, 3)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PodTopologySpread = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
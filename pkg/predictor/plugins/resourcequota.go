@@ -0,0 +1,272 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+const (
+	// NamespaceResourceQuotaName is the name of the plugin used in the plugin registry and configurations.
+	NamespaceResourceQuotaName = "NamespaceResourceQuota"
+
+	preFilterStateKeyNamespaceResourceQuota = "PreFilter" + NamespaceResourceQuotaName
+)
+
+const (
+	requestsPrefix = "requests."
+	limitsPrefix   = "limits."
+)
+
+// quotaPodsResources are the quota resources counting pods.
+var quotaPodsResources = []corev1.ResourceName{corev1.ResourcePods, "count/pods"}
+
+// NamespaceResourceQuota is a cap plugin that bounds the replicas by the ResourceQuotas of the
+// namespace of the workload, i.e. the hard limits minus the used. Assumed pods in the namespace are
+// counted as used, as they are not known by the quota controller.
+type NamespaceResourceQuota struct {
+	quotaLister corelisters.ResourceQuotaLister
+}
+
+var _ framework.PreFilterPlugin = &NamespaceResourceQuota{}
+var _ framework.CapPlugin = &NamespaceResourceQuota{}
+
+// NewNamespaceResourceQuota returns a NamespaceResourceQuota plugin.
+func NewNamespaceResourceQuota(_ json.RawMessage, handle framework.Handle) (framework.Plugin, error) {
+	return &NamespaceResourceQuota{
+		quotaLister: handle.SharedInformerFactory().Core().V1().ResourceQuotas().Lister(),
+	}, nil
+}
+
+// Name returns name of the plugin.
+func (pl *NamespaceResourceQuota) Name() string {
+	return NamespaceResourceQuotaName
+}
+
+// namespaceResourceQuotaState is computed at PreFilter and read back at Cap.
+type namespaceResourceQuotaState struct {
+	// assumed are the assumed replicas in the namespace of the workload.
+	assumed []framework.AssumedReplicas
+}
+
+// PreFilter collects the assumed replicas in the namespace of the workload from all the nodes.
+func (pl *NamespaceResourceQuota) PreFilter(_ context.Context, state *framework.CycleState, requirements *framework.Requirements, snapshot *framework.Snapshot) *framework.Status {
+	s := &namespaceResourceQuotaState{}
+	state.Write(preFilterStateKeyNamespaceResourceQuota, s)
+	if requirements.Namespace == "" {
+		return framework.NewStatus(framework.Skip)
+	}
+	for _, nodeInfo := range snapshot.List() {
+		for _, assumed := range nodeInfo.AssumedReplicas() {
+			if assumed.Pod.Namespace == requirements.Namespace {
+				s.assumed = append(s.assumed, assumed)
+			}
+		}
+	}
+	return nil
+}
+
+// Cap returns the smallest replicas allowed by the ResourceQuotas of the namespace.
+// It does not constrain anything for requests without a namespace, or namespaces without quotas.
+func (pl *NamespaceResourceQuota) Cap(_ context.Context, state *framework.CycleState, requirements *framework.Requirements, _ []*framework.NodeInfo, _ map[string]int64) (int64, *framework.Status) {
+	if requirements.Namespace == "" {
+		return 0, framework.NewStatus(framework.Skip)
+	}
+	quotas, err := pl.quotaLister.ResourceQuotas(requirements.Namespace).List(labels.Everything())
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+	if len(quotas) == 0 {
+		return 0, framework.NewStatus(framework.Skip)
+	}
+	data, err := state.Read(preFilterStateKeyNamespaceResourceQuota)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+	s, ok := data.(*namespaceResourceQuotaState)
+	if !ok {
+		return 0, framework.AsStatus(fmt.Errorf("%+v convert to namespaceResourceQuotaState error", data))
+	}
+	assumed := make([]corev1.ResourceRequirements, 0, len(s.assumed))
+	for _, a := range s.assumed {
+		assumed = append(assumed, podResources(a.Pod))
+	}
+
+	// the requests and limits are defaulted with the LimitRanges of the namespace already
+	requests, limits := requirements.Resources.Requests, requirements.Resources.Limits
+	bestEffort := isBestEffort(requirements.Resources)
+	// the quotas are sorted, so that the reported quota does not depend on the order of the cache
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].Name < quotas[j].Name })
+
+	var result int64 = math.MaxInt64
+	var reason string
+	for _, quota := range quotas {
+		if !quotaMatchesScopes(quota, bestEffort, requirements.PriorityClassName) {
+			continue
+		}
+		// the resources are sorted for the same reason
+		names := make([]string, 0, len(quota.Status.Hard))
+		for name := range quota.Status.Hard {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			hard := quota.Status.Hard[corev1.ResourceName(name)]
+			perReplica, required, ok := quotaUsagePerReplica(corev1.ResourceName(name), requests, limits)
+			if !ok {
+				continue
+			}
+			var replicas int64
+			var why string
+			switch {
+			case perReplica.IsZero() && required:
+				// the quota admission rejects pods not specifying a constrained compute resource
+				why = fmt.Sprintf("resource quota %s/%s requires %s to be specified", quota.Namespace, quota.Name, name)
+			case perReplica.IsZero():
+				continue
+			default:
+				free := hard.DeepCopy()
+				free.Sub(quota.Status.Used[corev1.ResourceName(name)])
+				for i, resources := range assumed {
+					if !quotaMatchesScopes(quota, isBestEffort(resources), s.assumed[i].Pod.Spec.PriorityClassName) {
+						continue
+					}
+					if used, _, ok := quotaUsagePerReplica(corev1.ResourceName(name), resources.Requests, resources.Limits); ok {
+						free.Sub(framework.MultiplyQuantity(used, s.assumed[i].Replicas))
+					}
+				}
+				if free.Sign() > 0 {
+					replicas = framework.DivideQuantity(free, perReplica)
+				}
+				why = fmt.Sprintf("resource quota %s/%s allows %d replicas by %s", quota.Namespace, quota.Name, replicas, name)
+			}
+			klog.V(5).Infof("%s", why)
+			if replicas < result {
+				result = replicas
+				reason = why
+			}
+		}
+	}
+	if reason == "" {
+		return 0, framework.NewStatus(framework.Skip)
+	}
+	return result, framework.NewStatus(framework.Success, reason)
+}
+
+// podResources returns the requests and limits of all the containers of the pod.
+func podResources(pod *corev1.Pod) corev1.ResourceRequirements {
+	result := corev1.ResourceRequirements{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}
+	for _, container := range pod.Spec.Containers {
+		framework.AddResourceList(result.Requests, container.Resources.Requests)
+		framework.AddResourceList(result.Limits, container.Resources.Limits)
+	}
+	return result
+}
+
+// quotaUsagePerReplica returns how much of the quota resource a replica uses, whether the quota
+// admission requires the resource to be specified, and false if the resource does not count pods.
+func quotaUsagePerReplica(name corev1.ResourceName, requests, limits corev1.ResourceList) (resource.Quantity, bool, bool) {
+	for _, podsResource := range quotaPodsResources {
+		if name == podsResource {
+			return *resource.NewQuantity(1, resource.DecimalSI), false, true
+		}
+	}
+
+	list := requests
+	resourceName := name
+	switch {
+	case strings.HasPrefix(string(name), requestsPrefix):
+		resourceName = corev1.ResourceName(strings.TrimPrefix(string(name), requestsPrefix))
+	case strings.HasPrefix(string(name), limitsPrefix):
+		list = limits
+		resourceName = corev1.ResourceName(strings.TrimPrefix(string(name), limitsPrefix))
+	case name != corev1.ResourceCPU && name != corev1.ResourceMemory && name != corev1.ResourceEphemeralStorage:
+		// object counts and storage are not used by the replicas
+		return resource.Quantity{}, false, false
+	}
+	if resourceName == corev1.ResourceStorage {
+		return resource.Quantity{}, false, false
+	}
+	required := resourceName == corev1.ResourceCPU || resourceName == corev1.ResourceMemory
+	return list[resourceName], required, true
+}
+
+// quotaMatchesScopes checks whether the replicas are tracked by the quota. Replicas are not terminating,
+// and their PriorityClass is matched the same as the quota admission does, i.e. a replica without one
+// matches the NotIn and DoesNotExist operators only.
+func quotaMatchesScopes(quota *corev1.ResourceQuota, bestEffort bool, priorityClassName string) bool {
+	scopes := make([]corev1.ResourceQuotaScope, 0, len(quota.Spec.Scopes))
+	scopes = append(scopes, quota.Spec.Scopes...)
+	if quota.Spec.ScopeSelector != nil {
+		for _, expr := range quota.Spec.ScopeSelector.MatchExpressions {
+			if expr.ScopeName == corev1.ResourceQuotaScopePriorityClass {
+				if !priorityClassMatches(expr, priorityClassName) {
+					return false
+				}
+				continue
+			}
+			if expr.Operator != corev1.ScopeSelectorOpExists {
+				return false
+			}
+			scopes = append(scopes, expr.ScopeName)
+		}
+	}
+
+	for _, scope := range scopes {
+		switch scope {
+		case corev1.ResourceQuotaScopeNotTerminating:
+		case corev1.ResourceQuotaScopeBestEffort:
+			if !bestEffort {
+				return false
+			}
+		case corev1.ResourceQuotaScopeNotBestEffort:
+			if bestEffort {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// priorityClassMatches checks whether the PriorityClass of the replicas is selected by the expression.
+func priorityClassMatches(expr corev1.ScopedResourceSelectorRequirement, priorityClassName string) bool {
+	switch expr.Operator {
+	case corev1.ScopeSelectorOpExists:
+		return priorityClassName != ""
+	case corev1.ScopeSelectorOpDoesNotExist:
+		return priorityClassName == ""
+	case corev1.ScopeSelectorOpIn:
+		return priorityClassName != "" && sets.NewString(expr.Values...).Has(priorityClassName)
+	case corev1.ScopeSelectorOpNotIn:
+		return priorityClassName == "" || !sets.NewString(expr.Values...).Has(priorityClassName)
+	}
+	return false
+}
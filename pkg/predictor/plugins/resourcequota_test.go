@@ -0,0 +1,226 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+func newResourceQuota(name string, scopes []corev1.ResourceQuotaScope, hard, used corev1.ResourceList) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec:       corev1.ResourceQuotaSpec{Hard: hard, Scopes: scopes},
+		Status:     corev1.ResourceQuotaStatus{Hard: hard, Used: used},
+	}
+}
+
+func TestNamespaceResourceQuota(t *testing.T) {
+	compute := newResourceQuota("compute", nil,
+		corev1.ResourceList{
+			corev1.ResourceRequestsCPU:     resource.MustParse("10"),
+			corev1.ResourceLimitsMemory:    resource.MustParse("20Gi"),
+			corev1.ResourceConfigMaps:      resource.MustParse("5"),
+			corev1.ResourceRequestsStorage: resource.MustParse("100Gi"),
+		},
+		corev1.ResourceList{
+			corev1.ResourceRequestsCPU:  resource.MustParse("6"),
+			corev1.ResourceLimitsMemory: resource.MustParse("4Gi"),
+		})
+	pods := newResourceQuota("pods", nil,
+		corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+		corev1.ResourceList{corev1.ResourcePods: resource.MustParse("7")})
+	bestEffort := newResourceQuota("best-effort", []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort},
+		corev1.ResourceList{corev1.ResourcePods: resource.MustParse("1")}, nil)
+	highPriority := newResourceQuota("high-priority", nil,
+		corev1.ResourceList{corev1.ResourcePods: resource.MustParse("2")}, nil)
+	highPriority.Spec.ScopeSelector = &corev1.ScopeSelector{MatchExpressions: []corev1.ScopedResourceSelectorRequirement{{
+		ScopeName: corev1.ResourceQuotaScopePriorityClass,
+		Operator:  corev1.ScopeSelectorOpIn,
+		Values:    []string{"high"},
+	}}}
+	notHighPriority := newResourceQuota("not-high-priority", nil,
+		corev1.ResourceList{corev1.ResourcePods: resource.MustParse("3")}, nil)
+	notHighPriority.Spec.ScopeSelector = &corev1.ScopeSelector{MatchExpressions: []corev1.ScopedResourceSelectorRequirement{{
+		ScopeName: corev1.ResourceQuotaScopePriorityClass,
+		Operator:  corev1.ScopeSelectorOpNotIn,
+		Values:    []string{"high"},
+	}}}
+
+	tests := []struct {
+		name          string
+		namespace     string
+		quotas        []*corev1.ResourceQuota
+		resources     corev1.ResourceRequirements
+		priorityClass string
+		pods          []*corev1.Pod
+		assumed       []framework.AssumedReplicas
+
+		wantSkip     bool
+		wantReplicas int64
+		wantReason   string
+	}{
+		{
+			name:      "no namespace",
+			quotas:    []*corev1.ResourceQuota{compute},
+			resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+			wantSkip:  true,
+		},
+		{
+			name:      "no quota",
+			namespace: "default",
+			resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+			wantSkip:  true,
+		},
+		{
+			name:      "bounded by requests.cpu",
+			namespace: "default",
+			quotas:    []*corev1.ResourceQuota{compute, pods},
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+			},
+			wantReplicas: 2,
+			wantReason:   "resource quota default/compute allows 2 replicas by requests.cpu",
+		},
+		{
+			name:      "bounded by pods",
+			namespace: "default",
+			quotas:    []*corev1.ResourceQuota{compute, pods},
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+			},
+			wantReplicas: 3,
+			wantReason:   "resource quota default/pods allows 3 replicas by pods",
+		},
+		{
+			name:      "missing limits.memory",
+			namespace: "default",
+			quotas:    []*corev1.ResourceQuota{compute},
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+			},
+			wantReplicas: 0,
+			wantReason:   "resource quota default/compute requires limits.memory to be specified",
+		},
+		{
+			name:      "assumed replicas are used",
+			namespace: "default",
+			quotas:    []*corev1.ResourceQuota{pods},
+			resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+			// the pods are counted by the used quota already
+			pods: []*corev1.Pod{newLabeledPod("default", nil)},
+			assumed: []framework.AssumedReplicas{
+				{Pod: newLabeledPod("default", nil), Replicas: 2},
+				{Pod: newLabeledPod("other", nil), Replicas: 5},
+			},
+			wantReplicas: 1,
+			wantReason:   "resource quota default/pods allows 1 replicas by pods",
+		},
+		{
+			name:         "scoped to best effort",
+			namespace:    "default",
+			quotas:       []*corev1.ResourceQuota{bestEffort},
+			wantReplicas: 1,
+			wantReason:   "resource quota default/best-effort allows 1 replicas by pods",
+		},
+		{
+			name:      "out of the scope",
+			namespace: "default",
+			quotas:    []*corev1.ResourceQuota{bestEffort},
+			resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+			wantSkip:  true,
+		},
+		{
+			name:          "scoped to the priority class",
+			namespace:     "default",
+			quotas:        []*corev1.ResourceQuota{highPriority, notHighPriority},
+			priorityClass: "high",
+			wantReplicas:  2,
+			wantReason:    "resource quota default/high-priority allows 2 replicas by pods",
+		},
+		{
+			name:          "scoped to the other priority classes",
+			namespace:     "default",
+			quotas:        []*corev1.ResourceQuota{highPriority, notHighPriority},
+			priorityClass: "low",
+			wantReplicas:  3,
+			wantReason:    "resource quota default/not-high-priority allows 3 replicas by pods",
+		},
+		{
+			name:         "without a priority class",
+			namespace:    "default",
+			quotas:       []*corev1.ResourceQuota{highPriority, notHighPriority},
+			wantReplicas: 3,
+			wantReason:   "resource quota default/not-high-priority allows 3 replicas by pods",
+		},
+		{
+			name:          "assumed replicas of the priority class are used",
+			namespace:     "default",
+			quotas:        []*corev1.ResourceQuota{highPriority},
+			priorityClass: "high",
+			assumed: []framework.AssumedReplicas{
+				{Pod: &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "high"},
+					Spec:       corev1.PodSpec{PriorityClassName: "high"},
+				}, Replicas: 1},
+				{Pod: newLabeledPod("default", nil), Replicas: 1},
+			},
+			wantReplicas: 1,
+			wantReason:   "resource quota default/high-priority allows 1 replicas by pods",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotaIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, quota := range tt.quotas {
+				if err := quotaIndexer.Add(quota); err != nil {
+					t.Fatal(err)
+				}
+			}
+			pl := &NamespaceResourceQuota{quotaLister: corelisters.NewResourceQuotaLister(quotaIndexer)}
+
+			requirements := &framework.Requirements{Namespace: tt.namespace, PriorityClassName: tt.priorityClass}
+			requirements.Resources = tt.resources
+			state := framework.NewCycleState()
+			nodeInfos := []*framework.NodeInfo{framework.NewNodeInfo(&corev1.Node{}, tt.pods...).WithAssumedReplicas(tt.assumed...)}
+			if status := pl.PreFilter(context.TODO(), state, requirements, framework.NewSnapshot(nodeInfos, nil)); !status.IsSuccess() && !status.IsSkip() {
+				t.Fatalf("Unexpected PreFilter status: %v", status.Message())
+			}
+			replicas, status := pl.Cap(context.TODO(), state, requirements, nodeInfos, nil)
+			if status.Code() == framework.Error {
+				t.Fatalf("Unexpected status: %v", status.Message())
+			}
+			if status.IsSkip() != tt.wantSkip {
+				t.Fatalf("Cap() status = %v, want skip %v", status.Code(), tt.wantSkip)
+			}
+			if tt.wantSkip {
+				return
+			}
+			if replicas != tt.wantReplicas || status.Message() != tt.wantReason {
+				t.Errorf("Cap() = %d %q, want %d %q", replicas, status.Message(), tt.wantReplicas, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+// TaintTolerationName is the name of the plugin used in the plugin registry and configurations.
+const TaintTolerationName = "TaintToleration"
+
+// TaintToleration is a filter plugin that checks whether the requirements tolerate the node taints.
+type TaintToleration struct{}
+
+var _ framework.FilterPlugin = &TaintToleration{}
+
+// NewTaintToleration returns a TaintToleration plugin.
+func NewTaintToleration(_ json.RawMessage, _ framework.Handle) (framework.Plugin, error) {
+	return &TaintToleration{}, nil
+}
+
+// Name returns name of the plugin.
+func (pl *TaintToleration) Name() string {
+	return TaintTolerationName
+}
+
+// Filter checks whether the requirements tolerate all the NoSchedule and NoExecute taints of the node.
+func (pl *TaintToleration) Filter(_ context.Context, _ *framework.CycleState, requirements *framework.Requirements, nodeInfo *framework.NodeInfo) *framework.Status {
+	taint, found := findUntoleratedTaint(nodeInfo.Node(), requirements.Tolerations)
+	if !found {
+		return nil
+	}
+	return framework.NewStatus(framework.Unschedulable,
+		fmt.Sprintf("node(s) had untolerated taint {%s: %s}", taint.Key, taint.Value))
+}
+
+// unschedulableTaint is the taint a pod has to tolerate to be placed on a cordoned node.
+var unschedulableTaint = corev1.Taint{
+	Key:    corev1.TaintNodeUnschedulable,
+	Effect: corev1.TaintEffectNoSchedule,
+}
+
+// findUntoleratedTaint returns the first NoSchedule or NoExecute taint of the node that is not tolerated.
+// Same as kube-scheduler, PreferNoSchedule taints never make a node infeasible, and a node marked as
+// unschedulable is treated as carrying the unschedulable taint.
+func findUntoleratedTaint(node *corev1.Node, tolerations []corev1.Toleration) (*corev1.Taint, bool) {
+	if node.Spec.Unschedulable && !tolerationsTolerateTaint(tolerations, &unschedulableTaint) {
+		return &unschedulableTaint, true
+	}
+
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect != corev1.TaintEffectNoSchedule && taint.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+		if !tolerationsTolerateTaint(tolerations, taint) {
+			return taint, true
+		}
+	}
+	return nil, false
+}
+
+func tolerationsTolerateTaint(tolerations []corev1.Toleration, taint *corev1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFindUntoleratedTaint(t *testing.T) {
+	gpu := corev1.Taint{Key: "gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	maintenance := corev1.Taint{Key: "maintenance", Value: "true", Effect: corev1.TaintEffectNoExecute}
+	spot := corev1.Taint{Key: "spot", Value: "true", Effect: corev1.TaintEffectPreferNoSchedule}
+	tolerateGPU := corev1.Toleration{Key: "gpu", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoSchedule}
+
+	tests := []struct {
+		name          string
+		taints        []corev1.Taint
+		unschedulable bool
+		tolerations   []corev1.Toleration
+		wantTaint     string
+	}{
+		{
+			name: "no taints",
+		},
+		{
+			name:      "untolerated NoSchedule",
+			taints:    []corev1.Taint{gpu},
+			wantTaint: "gpu",
+		},
+		{
+			name:      "untolerated NoExecute",
+			taints:    []corev1.Taint{maintenance},
+			wantTaint: "maintenance",
+		},
+		{
+			name:   "untolerated PreferNoSchedule is ignored",
+			taints: []corev1.Taint{spot},
+		},
+		{
+			name:        "tolerated NoSchedule",
+			taints:      []corev1.Taint{gpu},
+			tolerations: []corev1.Toleration{tolerateGPU},
+		},
+		{
+			name:        "toleration of another value",
+			taints:      []corev1.Taint{gpu},
+			tolerations: []corev1.Toleration{{Key: "gpu", Operator: corev1.TolerationOpEqual, Value: "false", Effect: corev1.TaintEffectNoSchedule}},
+			wantTaint:   "gpu",
+		},
+		{
+			name:        "partially tolerated taints",
+			taints:      []corev1.Taint{gpu, spot, maintenance},
+			tolerations: []corev1.Toleration{tolerateGPU},
+			wantTaint:   "maintenance",
+		},
+		{
+			name:        "exists toleration of the key",
+			taints:      []corev1.Taint{gpu},
+			tolerations: []corev1.Toleration{{Key: "gpu", Operator: corev1.TolerationOpExists}},
+		},
+		{
+			name:        "exists toleration of every taint",
+			taints:      []corev1.Taint{gpu, maintenance},
+			tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+		},
+		{
+			name:        "exists toleration of another effect",
+			taints:      []corev1.Taint{maintenance},
+			tolerations: []corev1.Toleration{{Key: "maintenance", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+			wantTaint:   "maintenance",
+		},
+		{
+			name:          "cordoned node",
+			unschedulable: true,
+			wantTaint:     corev1.TaintNodeUnschedulable,
+		},
+		{
+			name:          "cordoned node with a toleration of the unschedulable taint",
+			unschedulable: true,
+			tolerations:   []corev1.Toleration{{Key: corev1.TaintNodeUnschedulable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+		},
+		{
+			name:          "cordoned node with other taints tolerated",
+			unschedulable: true,
+			taints:        []corev1.Taint{gpu},
+			tolerations:   []corev1.Toleration{tolerateGPU},
+			wantTaint:     corev1.TaintNodeUnschedulable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+				Spec:       corev1.NodeSpec{Taints: tt.taints, Unschedulable: tt.unschedulable},
+			}
+			taint, found := findUntoleratedTaint(node, tt.tolerations)
+			if found != (tt.wantTaint != "") {
+				t.Fatalf("findUntoleratedTaint() found = %v, want taint %q", found, tt.wantTaint)
+			}
+			if found && taint.Key != tt.wantTaint {
+				t.Errorf("findUntoleratedTaint() taint = %s, want %s", taint.Key, tt.wantTaint)
+			}
+		})
+	}
+}
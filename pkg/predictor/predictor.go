@@ -15,168 +15,668 @@ package predictor
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
 	"k8s.io/client-go/informers"
 	informer "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	schedulinglisters "k8s.io/client-go/listers/scheduling/v1"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
-	appsapi "github.com/clusternet/clusternet/pkg/apis/apps/v1alpha1"
+	predictorcache "github.com/clusternet/sample-controller/pkg/predictor/cache"
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+	"github.com/clusternet/sample-controller/pkg/predictor/metrics"
 )
 
 // PredictorServer is a server for predict request.
 type PredictorServer struct {
 	Port uint
-	Ctx  context.Context
 
 	k8sClient    kubernetes.Interface
 	factory      informers.SharedInformerFactory
 	nodeInformer informer.NodeInformer
+	podInformer  informer.PodInformer
+	// priorityClassLister resolves the priority class names of the requests
+	priorityClassLister schedulinglisters.PriorityClassLister
+	// limitRangeLister defaults the resources of the requests
+	limitRangeLister corelisters.LimitRangeLister
+	cache            *predictorcache.Cache
+	framework        *framework.Framework
+
+	maxRequestBytes     int64
+	shutdownGracePeriod time.Duration
+	serving             servingOptions
+	clientCA            *dynamiccertificates.DynamicFileCAContent
+	authenticator       authenticator.Request
+	authorizer          authorizer.Authorizer
+	authz               authorizationAttributes
+	recorder            *recorder
+	// reservations is nil when reservations are disabled
+	reservations *reservations
+	// name is the name of the cluster, when the predictor serves multiple clusters
+	name string
+	// clusters are the clusters requests are routed to, when the predictor serves multiple clusters.
+	// The predictor then only authorizes and routes the requests, without a cluster of its own.
+	clusters *clusterSet
+	// synced is set to 1 once all the informer caches have synced
+	synced int32
 }
 
+var _ framework.Handle = &PredictorServer{}
+
 // NewPredictorServer return a predictor server
-func NewPredictorServer(options PredictorOptions) (*PredictorServer, error) {
-	restConfig, err := clientcmd.BuildConfigFromFlags(options.MasterURL, options.KubeconfigPath)
+func NewPredictorServer(options PredictorOptions, registry framework.Registry) (*PredictorServer, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	config, err := LoadConfiguration(options.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Register()
+
+	var p *PredictorServer
+	var kubeClient kubernetes.Interface
+	if options.ClustersKubeconfig == "" {
+		restConfig, err := clientcmd.BuildConfigFromFlags(options.MasterURL, options.KubeconfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("error of create kubernetes restConfig : %v", err)
+		}
+		p, err = newClusterServer("", restConfig, options, config, registry)
+		if err != nil {
+			return nil, err
+		}
+		kubeClient = p.k8sClient
+	} else {
+		// the cluster the predictor runs in is only needed to review the tokens and the access of callers
+		if options.AuthenticationTokenWebhook || options.AuthorizationMode == AuthorizationModeWebhook {
+			restConfig, err := clientcmd.BuildConfigFromFlags(options.MasterURL, options.KubeconfigPath)
+			if err != nil {
+				return nil, fmt.Errorf("error of create kubernetes restConfig : %v", err)
+			}
+			if kubeClient, err = kubernetes.NewForConfig(restConfig); err != nil {
+				return nil, fmt.Errorf("error of create kubernetes client : %v", err)
+			}
+		}
+		p = &PredictorServer{}
+		p.clusters = newClusterSet(options.ClustersKubeconfig, func(name string, restConfig *rest.Config) (*PredictorServer, error) {
+			cluster, err := newClusterServer(name, restConfig, options, config, registry)
+			if err != nil {
+				return nil, err
+			}
+			cluster.maxRequestBytes = p.maxRequestBytes
+			cluster.authenticator = p.authenticator
+			cluster.authorizer = p.authorizer
+			cluster.authz = p.authz
+			return cluster, nil
+		})
+	}
+
+	p.Port = options.Port
+	p.maxRequestBytes = options.MaxRequestBytes
+	p.shutdownGracePeriod = options.ShutdownGracePeriod
+	p.serving = servingOptions{
+		certFile:          options.TLSCertFile,
+		keyFile:           options.TLSPrivateKeyFile,
+		requireClientCert: options.RequireClientCert,
+	}
+	p.authz = authorizationAttributes{
+		verb:     options.AuthorizationVerb,
+		group:    options.AuthorizationGroup,
+		resource: options.AuthorizationResource,
+	}
+
+	// client certificates are both verified by the tls handshake and used as the identity of callers
+	var clientCA dynamiccertificates.CAContentProvider
+	if options.ClientCAFile != "" {
+		p.clientCA, err = dynamiccertificates.NewDynamicCAContentFromFile("client-ca", options.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error of load client CA : %v", err)
+		}
+		clientCA = p.clientCA
+	}
+	p.authenticator, err = newAuthenticator(options, kubeClient, clientCA)
 	if err != nil {
-		return nil, fmt.Errorf("error of create kubernetes restConfig : %v", err)
+		return nil, err
+	}
+	p.authorizer, err = newAuthorizer(options, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// newClusterServer returns the predictor of a cluster, which is named when the predictor serves
+// multiple clusters. Its records are kept in a subdirectory of the record directory named by the cluster.
+func newClusterServer(name string, restConfig *rest.Config, options PredictorOptions, config *PredictorConfiguration,
+	registry framework.Registry) (*PredictorServer, error) {
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error of create kubernetes client : %v", err)
 	}
-	kubeClient := kubernetes.NewForConfigOrDie(restConfig)
 	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
-	ctx := context.Background()
-	return &PredictorServer{
-		Port:         options.Port,
-		Ctx:          ctx,
-		k8sClient:    kubeClient,
-		factory:      informerFactory,
-		nodeInformer: informerFactory.Core().V1().Nodes(),
-	}, nil
+	nodeCache := predictorcache.New()
+	podInformer := informerFactory.Core().V1().Pods()
+	podInformer.Informer().AddEventHandler(nodeCache.PodEventHandler())
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	nodeInformer.Informer().AddEventHandler(nodeCache.NodeEventHandler())
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { metrics.NodeCacheUpdated(name) },
+		UpdateFunc: func(interface{}, interface{}) { metrics.NodeCacheUpdated(name) },
+		DeleteFunc: func(interface{}) { metrics.NodeCacheUpdated(name) },
+	})
+
+	p := &PredictorServer{
+		name:                name,
+		k8sClient:           kubeClient,
+		factory:             informerFactory,
+		nodeInformer:        nodeInformer,
+		podInformer:         podInformer,
+		priorityClassLister: informerFactory.Scheduling().V1().PriorityClasses().Lister(),
+		limitRangeLister:    informerFactory.Core().V1().LimitRanges().Lister(),
+		cache:               nodeCache,
+	}
+	if options.RecordDir != "" {
+		p.recorder, err = newRecorder(filepath.Join(options.RecordDir, name), options.RecordMaxFiles, informerFactory)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.ReservationTTL > 0 {
+		p.reservations = newReservations(name, options.ReservationTTL, options.ReservationMaxReplicas, clock.RealClock{})
+		podInformer.Informer().AddEventHandler(p.reservations.podEventHandler())
+	}
+	p.framework, err = framework.NewFramework(registry, config.Plugins, config.PluginConfig, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ClientSet returns the kubernetes clientSet of the cluster.
+func (p *PredictorServer) ClientSet() kubernetes.Interface {
+	return p.k8sClient
 }
 
-func (p *PredictorServer) Run() error {
+// SharedInformerFactory returns the shared informer factory of the cluster.
+func (p *PredictorServer) SharedInformerFactory() informers.SharedInformerFactory {
+	return p.factory
+}
+
+// apiEndpoints are the endpoints routed to a cluster by the ClusterHeader.
+var apiEndpoints = []string{"/accept", "/accept-batch", "/unschedul", "/release"}
+
+// Handler returns the http handler that serves all the endpoints of the predictor.
+func (p *PredictorServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", p.Healthz)
+	mux.HandleFunc("/readyz", p.Readyz)
+	mux.HandleFunc("/version", p.Version)
+	// the clusters of a predictor share its metrics, labeled by cluster, instead of serving their own
+	if p.name == "" {
+		mux.Handle("/metrics", metrics.Handler())
+	}
+	if p.clusters != nil {
+		// the clusters are listed to the same callers as are allowed to predict against them
+		mux.HandleFunc("/clusters", func(w http.ResponseWriter, r *http.Request) {
+			if p.authorize(w, r) {
+				p.clusters.List(w, r)
+			}
+		})
+		mux.HandleFunc("/clusters/", p.clusters.routeByPath)
+		for _, endpoint := range apiEndpoints {
+			mux.HandleFunc(endpoint, p.clusters.routeByHeader)
+		}
+		return mux
+	}
+	mux.HandleFunc("/accept", p.instrument("accept", p.serveAPI(p.MaxAcceptableReplicas)))
+	mux.HandleFunc("/accept-batch", p.instrument("accept-batch", p.serveAPI(p.BatchMaxAcceptableReplicas)))
+	mux.HandleFunc("/unschedul", p.instrument("unschedul", p.serveAPI(p.UnschedulableReplicas)))
+	mux.HandleFunc("/release", p.instrument("release", p.serveAPI(p.Release)))
+	return mux
+}
+
+// Run serves the predictor until ctx is done, then waits for in-flight requests
+// to finish within the shutdown grace period.
+func (p *PredictorServer) Run(ctx context.Context) error {
 	klog.Infof("Run predictor server with port %d ... ", p.Port)
 
+	// informers and certificate controllers are stopped after the server has shut down,
+	// so that in-flight requests are still served from the caches
 	stopper := make(chan struct{})
 	defer close(stopper)
-	informer := p.nodeInformer.Informer()
-	go p.factory.Start(stopper)
-	if !cache.WaitForCacheSync(stopper, informer.HasSynced) {
-		klog.Info("time our waiting for cache to sync")
+
+	// the clusters are loaded before the predictor turns ready, while their caches sync on their own
+	if p.clusters != nil {
+		if err := p.clusters.reload(); err != nil {
+			return err
+		}
+		atomic.StoreInt32(&p.synced, 1)
 	}
 
-	http.HandleFunc("/accept", p.MaxAcceptableReplicas)
-	http.HandleFunc("/unschedul", p.UnschedulableReplicas)
-	err := http.ListenAndServe(fmt.Sprintf(":%d", p.Port), nil)
+	tlsConfig, err := newTLSConfig(p.serving, p.clientCA, stopper)
 	if err != nil {
 		return err
 	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", p.Port))
+	if err != nil {
+		return fmt.Errorf("error of listen on port %d : %v", p.Port, err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	// serve before the caches have synced, so that probes could tell a starting predictor from a dead one
+	server := &http.Server{Handler: p.Handler()}
+	errCh := make(chan error, 1)
+	go func() {
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+
+	if p.clusters != nil {
+		go p.clusters.run(stopper)
+	} else {
+		p.start(stopper)
+	}
+
+	select {
+	case err = <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	klog.Infof("shutting down predictor server, waiting up to %v for in-flight requests", p.shutdownGracePeriod)
+	// fail readiness probes first, so that no new requests are routed to the predictor
+	atomic.StoreInt32(&p.synced, 0)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), p.shutdownGracePeriod)
+	defer cancel()
+	if err = server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error of shut down predictor server : %v", err)
+	}
+	klog.Info("predictor server is stopped")
 	return nil
 }
 
+// start starts the informers of the cluster, and marks the predictor synced once their caches have synced.
+func (p *PredictorServer) start(stopCh <-chan struct{}) {
+	p.nodeInformer.Informer()
+	p.podInformer.Informer()
+	go p.factory.Start(stopCh)
+	if p.recorder != nil {
+		go p.recorder.run(stopCh)
+	}
+	if p.reservations != nil {
+		go p.reservations.run(stopCh)
+	}
+	go func() {
+		if p.waitForCacheSync(stopCh) {
+			atomic.StoreInt32(&p.synced, 1)
+			if p.name != "" {
+				klog.Infof("caches of cluster %s are synced, predictor is ready for it", p.name)
+				return
+			}
+			klog.Info("caches are synced, predictor is ready")
+		}
+	}()
+}
+
 // MaxAcceptAbleReplicas is a http handler for max replicas reqeust
 func (p *PredictorServer) MaxAcceptableReplicas(w http.ResponseWriter, r *http.Request) {
-	var matchNode = make(map[string]int64)
-	var require appsapi.ReplicaRequirements
-	var maxReplicas int64
+	var require MaxAcceptableReplicasRequest
+	if err := p.decodeRequest(r, &require); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if require.ReservationID != "" && p.reservations == nil {
+		writeError(w, errReservationsDisabled)
+		return
+	}
+	if err := p.resolvePriority(&require); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := p.applyLimitRanges(&require); err != nil {
+		writeError(w, err)
+		return
+	}
 
-	requestBody, err := ioutil.ReadAll(r.Body)
+	snapshot := p.cache.Snapshot()
+	if p.reservations != nil {
+		if require.ReservationID != "" {
+			p.reservations.predictMu.Lock()
+			defer p.reservations.predictMu.Unlock()
+		}
+		snapshot = p.reservations.assume(snapshot, sets.NewString(require.ReservationID))
+	}
+	result, err := p.predict(r.Context(), &require, snapshot)
 	if err != nil {
-		klog.Info("error of read request body : ", err)
+		klog.Errorf("error of predict replicas : %v", err)
+		writeError(w, apierrors.NewInternalError(err))
+		return
 	}
+	var reservation *Reservation
+	if require.ReservationID != "" {
+		reservation = p.reservations.reserve(require.ReservationID, &require, placeReplicas(snapshot, result, result.Replicas))
+	}
+	writeAcceptableReplicas(w, r, result, reservation)
+}
 
-	err = json.Unmarshal(requestBody, &require)
+// predict runs the framework against the snapshot of the nodes and records the metrics of the result.
+func (p *PredictorServer) predict(ctx context.Context, require *MaxAcceptableReplicasRequest, snapshot *framework.Snapshot) (*framework.Result, error) {
+	result, err := p.framework.Predict(ctx, newRequirements(require), snapshot)
+	if p.recorder != nil {
+		p.recorder.record(require, snapshot, result, err)
+	}
 	if err != nil {
-		klog.Info("error of read request body : ", err)
+		return nil, err
+	}
+	metrics.PredictedReplicas.WithLabelValues(p.name).Observe(float64(result.Replicas))
+	rejections := make(map[string]int)
+	for _, status := range result.Rejections {
+		rejections[status.Plugin()]++
 	}
-	labelSelector := labels.NewSelector()
-	for k, v := range require.NodeSelector {
-		requ, _ := labels.NewRequirement(k, selection.Equals, []string{v})
-		labelSelector.Add(*requ)
+	for plugin, count := range rejections {
+		metrics.RejectedNodesTotal.WithLabelValues(p.name, plugin).Add(float64(count))
 	}
-	nodeList, err := p.nodeInformer.Lister().List(labelSelector)
+	return result, nil
+}
+
+// newRequirements converts a request to the requirements of the framework.
+func newRequirements(require *MaxAcceptableReplicasRequest) *framework.Requirements {
+	return &framework.Requirements{
+		ReplicaRequirements:       require.ReplicaRequirements,
+		Namespace:                 require.Namespace,
+		PodLabels:                 require.PodLabels,
+		TopologySpreadConstraints: require.TopologySpreadConstraints,
+		Priority:                  require.Priority,
+		PreemptionPolicy:          require.PreemptionPolicy,
+		PriorityClassName:         require.PriorityClassName,
+	}
+}
+
+// resolvePriority sets the priority and the preemption policy of the request from its PriorityClass.
+func (p *PredictorServer) resolvePriority(require *MaxAcceptableReplicasRequest) *apierrors.StatusError {
+	if require.PriorityClassName == "" {
+		return nil
+	}
+	class, err := p.priorityClassLister.Get(require.PriorityClassName)
 	if err != nil {
-		klog.Info("error of list node : ", err)
+		if apierrors.IsNotFound(err) {
+			return apierrors.NewBadRequest(fmt.Sprintf("priority class %q is not found", require.PriorityClassName))
+		}
+		klog.Errorf("error of get priority class %s : %v", require.PriorityClassName, err)
+		return apierrors.NewInternalError(err)
 	}
-	for _, n := range nodeList {
-		if replicas := p.checkNodeResource(n, require); replicas > 0 {
-			matchNode[n.Name] = replicas
+	if require.Priority != nil && *require.Priority != class.Value {
+		return apierrors.NewBadRequest(fmt.Sprintf("priority %d does not match the value %d of priority class %q",
+			*require.Priority, class.Value, class.Name))
+	}
+	priority := class.Value
+	require.Priority = &priority
+	if require.PreemptionPolicy == nil {
+		require.PreemptionPolicy = class.PreemptionPolicy
+	}
+	return nil
+}
+
+// applyLimitRanges defaults the requests and limits of the request with the container LimitRanges of its
+// namespace, the same as the LimitRanger admission plugin does for the pods, so that every plugin sees them.
+func (p *PredictorServer) applyLimitRanges(require *MaxAcceptableReplicasRequest) *apierrors.StatusError {
+	var limitRanges []*corev1.LimitRange
+	if require.Namespace != "" {
+		var err error
+		limitRanges, err = p.limitRangeLister.LimitRanges(require.Namespace).List(labels.Everything())
+		if err != nil {
+			klog.Errorf("error of list limit ranges of namespace %s : %v", require.Namespace, err)
+			return apierrors.NewInternalError(err)
 		}
 	}
-	for _, v := range matchNode {
-		maxReplicas += v
+	require.Resources = defaultResources(require.Resources, limitRanges)
+	return nil
+}
+
+// defaultResources returns the resources of a replica once defaulted by the API server and the
+// container LimitRanges, i.e. missing requests default to the limits, missing limits default to the
+// default limits, and then the missing requests to the default requests.
+func defaultResources(resources corev1.ResourceRequirements, limitRanges []*corev1.LimitRange) corev1.ResourceRequirements {
+	requests := resources.Requests.DeepCopy()
+	if requests == nil {
+		requests = corev1.ResourceList{}
 	}
-	clusterMax := p.checkClusterResource(require, nodeList, matchNode)
-	if clusterMax < maxReplicas {
-		maxReplicas = clusterMax
+	limits := resources.Limits.DeepCopy()
+	if limits == nil {
+		limits = corev1.ResourceList{}
 	}
-	if _, err = w.Write([]byte(strconv.FormatInt(maxReplicas, 10))); err != nil {
-		klog.Error(err)
+	for name, quantity := range limits {
+		if _, ok := requests[name]; !ok {
+			requests[name] = quantity.DeepCopy()
+		}
 	}
+
+	for _, limitRange := range limitRanges {
+		for _, item := range limitRange.Spec.Limits {
+			if item.Type != corev1.LimitTypeContainer {
+				continue
+			}
+			for name, quantity := range item.Default {
+				if _, ok := limits[name]; !ok {
+					limits[name] = quantity.DeepCopy()
+				}
+			}
+			for name, quantity := range item.DefaultRequest {
+				if _, ok := requests[name]; !ok {
+					requests[name] = quantity.DeepCopy()
+				}
+			}
+			// the default requests of a LimitRange default to its default limits
+			for name, quantity := range item.Default {
+				if _, ok := requests[name]; !ok {
+					requests[name] = quantity.DeepCopy()
+				}
+			}
+		}
+	}
+
+	// the empty lists are left out, the same as in the request
+	resources.Requests, resources.Limits = nil, nil
+	if len(requests) > 0 {
+		resources.Requests = requests
+	}
+	if len(limits) > 0 {
+		resources.Limits = limits
+	}
+	return resources
 }
 
+// UnschedulableReplicas is a http handler for unschedulable replicas request
 func (p *PredictorServer) UnschedulableReplicas(w http.ResponseWriter, r *http.Request) {
-	// TODO: add real logic
+	var workload UnschedulableReplicasRequest
+	if err := p.decodeRequest(r, &workload); err != nil {
+		writeError(w, err)
+		return
+	}
+	if workload.Namespace == "" || workload.Name == "" {
+		writeError(w, apierrors.NewBadRequest("namespace and name of the workload are required"))
+		return
+	}
+
+	var err error
+	selector := labels.Everything()
+	if workload.LabelSelector != nil {
+		selector, err = metav1.LabelSelectorAsSelector(workload.LabelSelector)
+		if err != nil {
+			writeError(w, apierrors.NewBadRequest(fmt.Sprintf("invalid label selector: %v", err)))
+			return
+		}
+	}
+	pods, err := p.podInformer.Lister().Pods(workload.Namespace).List(selector)
+	if err != nil {
+		klog.Errorf("error of list pod : %v", err)
+		writeError(w, apierrors.NewInternalError(err))
+		return
+	}
+
+	var unschedulable int32
+	for _, pod := range pods {
+		if isPodOwnedBy(pod, workload) && isPodUnschedulable(pod) {
+			unschedulable++
+		}
+	}
+	klog.V(4).Infof("workload %s %s/%s has %d unschedulable replicas",
+		workload.Kind, workload.Namespace, workload.Name, unschedulable)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(UnschedulableReplicasResponse{
+		Namespace:             workload.Namespace,
+		Name:                  workload.Name,
+		UnschedulableReplicas: unschedulable,
+	}); err != nil {
+		klog.Error(err)
+	}
 }
 
-func (p *PredictorServer) checkClusterResource(_ appsapi.ReplicaRequirements, nodes []*corev1.Node, matchNode map[string]int64) int64 {
-	// If your cluster node set annotation "tke.cloud.tencent.com/available-ip-count" value represent available ip count
-	var ipCount int64
-	for _, n := range nodes {
-		if _, isok := matchNode[n.Name]; isok {
-			ip, _ := strconv.Atoi(n.Labels["tke.cloud.tencent.com/available-ip-count"])
-			ipCount += int64(ip)
+// isPodOwnedBy checks whether the pod belongs to the workload through its controller owner reference.
+// Pods without a controller are only matched when the workload carries a label selector.
+func isPodOwnedBy(pod *corev1.Pod, workload UnschedulableReplicasRequest) bool {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return workload.LabelSelector != nil
+	}
+
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil {
+		return false
+	}
+	if gv.Group == workload.Group && owner.Kind == workload.Kind && owner.Name == workload.Name {
+		return true
+	}
+	// pods of a Deployment are controlled by its ReplicaSets, which are named "<deployment>-<pod-template-hash>",
+	// so that the ReplicaSets of a Deployment named with another "-" segment, e.g. "web-api", are not matched
+	if workload.Group == "apps" && workload.Kind == "Deployment" &&
+		gv.Group == "apps" && owner.Kind == "ReplicaSet" {
+		if !strings.HasPrefix(owner.Name, workload.Name+"-") {
+			return false
+		}
+		hash := strings.TrimPrefix(owner.Name, workload.Name+"-")
+		if podTemplateHash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok {
+			return hash == podTemplateHash
 		}
+		return !strings.Contains(hash, "-")
 	}
-	// TODO: add other logic
-	return ipCount
+	return false
 }
 
-func (p *PredictorServer) checkNodeResource(n *corev1.Node, require appsapi.ReplicaRequirements) int64 {
-	var replicas int64 = 1000
-	var tt bool = false
-	if len(n.Spec.Taints) != 0 {
-		for _, taint := range n.Spec.Taints {
-			for _, toleration := range require.Tolerations {
-				if toleration.ToleratesTaint(&taint) {
-					tt = true
-					break
-				}
-			}
+// isPodUnschedulable checks whether the pod is pending because the scheduler could not find a node for it.
+func isPodUnschedulable(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Spec.NodeName != "" || pod.Status.Phase != corev1.PodPending {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled {
+			return condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable
 		}
-		if !tt {
-			klog.Info("there is no match node for requirements with taint/toleration")
-			return 0
-		}
-	}
-
-	// Some node annotation represent resource is not enough
-	if n.Annotations["tke.cloud.tencent.com/res-cloud-hssd"] == "false" {
-		return 0
-	}
-	for resourceName, resource := range require.Resources.Requests {
-		if resource.Cmp(*n.Status.Capacity.Name(resourceName, resource.Format)) > 0 {
-			klog.Infof("node %s resource %s(%d) is not enough for request %d",
-				n.Name, resourceName, n.Status.Capacity.Name(resourceName, resource.Format).Value(), resource.Value())
-			return 0
-		} else {
-			//Use resource Value() beause resource is too big in eks cluster, will concern int64
-			//when pod request resource less then 1c , will use 1c to estimat
-			multiple := n.Status.Capacity.Name(resourceName, resource.Format).Value() / resource.Value()
-			klog.Infof("resource %s: node(%s) has %d, pod need %d, replicas is %d.",
-				resourceName, n.Name, n.Status.Capacity.Name(resourceName, resource.Format).Value(), resource.Value(), multiple)
-			if replicas > multiple {
-				replicas = multiple
-			}
+	}
+	return false
+}
+
+// serveAPI wraps a predictor API handler with the checks shared by all of them.
+func (p *PredictorServer) serveAPI(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !p.authorize(w, r) {
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, apierrors.NewMethodNotSupported(schema.GroupResource{Resource: r.URL.Path}, r.Method))
+			return
+		}
+		if atomic.LoadInt32(&p.synced) == 0 {
+			w.Header().Set("Retry-After", "1")
+			writeError(w, apierrors.NewServiceUnavailable("informer caches have not synced yet"))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// statusRecorder records the http code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// instrument records the count and latency of the requests served by the handler.
+func (p *PredictorServer) instrument(endpoint string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		handler(recorder, r)
+		metrics.RequestDuration.WithLabelValues(p.name, endpoint).Observe(metrics.SinceInSeconds(start))
+		metrics.RequestsTotal.WithLabelValues(p.name, endpoint, strconv.Itoa(recorder.code)).Inc()
+	}
+}
+
+// decodeRequest reads the json request body into obj.
+func (p *PredictorServer) decodeRequest(r *http.Request, obj interface{}) *apierrors.StatusError {
+	// read one more byte to find out whether the body exceeds the limit
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, p.maxRequestBytes+1))
+	if err != nil {
+		klog.Errorf("error of read request body : %v", err)
+		return apierrors.NewBadRequest(fmt.Sprintf("error of read request body: %v", err))
+	}
+	if int64(len(body)) > p.maxRequestBytes {
+		return apierrors.NewRequestEntityTooLargeError(fmt.Sprintf("limit is %d bytes", p.maxRequestBytes))
+	}
+	if err = json.Unmarshal(body, obj); err != nil {
+		return apierrors.NewBadRequest(fmt.Sprintf("error of decode request body: %v", err))
+	}
+	return nil
+}
+
+// waitForCacheSync waits for all the informers started by the factory, including those of plugins.
+func (p *PredictorServer) waitForCacheSync(stopCh <-chan struct{}) bool {
+	allSynced := true
+	for informerType, synced := range p.factory.WaitForCacheSync(stopCh) {
+		if !synced {
+			klog.Errorf("cache of %v is not synced", informerType)
+			metrics.InformerSynced.WithLabelValues(p.name, informerType.String()).Set(0)
+			allSynced = false
+			continue
 		}
+		metrics.InformerSynced.WithLabelValues(p.name, informerType.String()).Set(1)
 	}
-	return replicas
+	return allSynced
 }
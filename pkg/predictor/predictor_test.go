@@ -0,0 +1,643 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	predictorcache "github.com/clusternet/sample-controller/pkg/predictor/cache"
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+	"github.com/clusternet/sample-controller/pkg/predictor/plugins"
+)
+
+// newTestServer returns a predictor server backed by a fake clientset.
+func newTestServer() *PredictorServer {
+	client := fake.NewSimpleClientset()
+	factory := informers.NewSharedInformerFactory(client, 0)
+	return &PredictorServer{
+		k8sClient:           client,
+		factory:             factory,
+		nodeInformer:        factory.Core().V1().Nodes(),
+		podInformer:         factory.Core().V1().Pods(),
+		priorityClassLister: factory.Scheduling().V1().PriorityClasses().Lister(),
+		limitRangeLister:    factory.Core().V1().LimitRanges().Lister(),
+		cache:               predictorcache.New(),
+		maxRequestBytes:     1 << 20,
+		shutdownGracePeriod: time.Second,
+	}
+}
+
+func TestIsPodOwnedBy(t *testing.T) {
+	deployment := UnschedulableReplicasRequest{
+		GroupVersionKind: metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Namespace:        "default",
+		Name:             "web",
+	}
+	statefulSet := UnschedulableReplicasRequest{
+		GroupVersionKind: metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+		Namespace:        "default",
+		Name:             "web",
+	}
+	withSelector := deployment
+	withSelector.LabelSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+	tests := []struct {
+		name     string
+		owner    *metav1.OwnerReference
+		labels   map[string]string
+		workload UnschedulableReplicasRequest
+		want     bool
+	}{
+		{
+			name:     "replicaset of the deployment",
+			owner:    &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-5d4f8c6b9"},
+			labels:   map[string]string{"pod-template-hash": "5d4f8c6b9"},
+			workload: deployment,
+			want:     true,
+		},
+		{
+			name:     "replicaset of the deployment without pod-template-hash",
+			owner:    &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-5d4f8c6b9"},
+			workload: deployment,
+			want:     true,
+		},
+		{
+			name:     "replicaset of another deployment",
+			owner:    &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "webapp-5d4f8c6b9"},
+			workload: deployment,
+			want:     false,
+		},
+		{
+			name:     "replicaset of another deployment sharing the prefix",
+			owner:    &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-api-5d4f8c6b9"},
+			labels:   map[string]string{"pod-template-hash": "5d4f8c6b9"},
+			workload: deployment,
+			want:     false,
+		},
+		{
+			name:     "replicaset of another deployment sharing the prefix without pod-template-hash",
+			owner:    &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-api-5d4f8c6b9"},
+			workload: deployment,
+			want:     false,
+		},
+		{
+			name:     "statefulset controller",
+			owner:    &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "web"},
+			workload: statefulSet,
+			want:     true,
+		},
+		{
+			name:     "same kind in another group",
+			owner:    &metav1.OwnerReference{APIVersion: "apps.kruise.io/v1beta1", Kind: "StatefulSet", Name: "web"},
+			workload: statefulSet,
+			want:     false,
+		},
+		{
+			name:     "bare pod without selector",
+			workload: deployment,
+			want:     false,
+		},
+		{
+			name:     "bare pod with selector",
+			workload: withSelector,
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-0", Labels: tt.labels}}
+			if tt.owner != nil {
+				controller := true
+				tt.owner.Controller = &controller
+				pod.OwnerReferences = []metav1.OwnerReference{*tt.owner}
+			}
+			if got := isPodOwnedBy(pod, tt.workload); got != tt.want {
+				t.Errorf("isPodOwnedBy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPodUnschedulable(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodeName string
+		phase    corev1.PodPhase
+		reason   string
+		want     bool
+	}{
+		{
+			name:   "pending and unschedulable",
+			phase:  corev1.PodPending,
+			reason: corev1.PodReasonUnschedulable,
+			want:   true,
+		},
+		{
+			name:   "pending for scheduling gates",
+			phase:  corev1.PodPending,
+			reason: "SchedulingGated",
+			want:   false,
+		},
+		{
+			name:     "bound to a node",
+			nodeName: "node-1",
+			phase:    corev1.PodPending,
+			reason:   corev1.PodReasonUnschedulable,
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{NodeName: tt.nodeName},
+				Status: corev1.PodStatus{
+					Phase: tt.phase,
+					Conditions: []corev1.PodCondition{
+						{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: tt.reason},
+					},
+				},
+			}
+			if got := isPodUnschedulable(pod); got != tt.want {
+				t.Errorf("isPodUnschedulable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWantsJSON(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "", want: false},
+		{accept: "*/*", want: false},
+		{accept: "text/plain", want: false},
+		{accept: "application/json", want: true},
+		{accept: "application/json; charset=utf-8", want: true},
+		{accept: "text/plain, application/json", want: true},
+		{accept: "application/json;q=0.5, text/plain", want: false},
+		{accept: "application/json;q=0", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := wantsJSON(tt.accept); got != tt.want {
+				t.Errorf("wantsJSON(%q) = %v, want %v", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeAPI(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		unsynced bool
+		wantCode int
+	}{
+		{
+			name:     "valid request",
+			method:   http.MethodPost,
+			body:     `{"namespace": "default", "name": "web"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "method not allowed",
+			method:   http.MethodGet,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:     "caches not synced",
+			method:   http.MethodPost,
+			body:     `{"namespace": "default", "name": "web"}`,
+			unsynced: true,
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:     "malformed json",
+			method:   http.MethodPost,
+			body:     `{"namespace": `,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "body too large",
+			method:   http.MethodPost,
+			body:     `{"namespace": "default", "name": "` + strings.Repeat("x", 64) + `"}`,
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PredictorServer{maxRequestBytes: 64, synced: 1}
+			if tt.unsynced {
+				p.synced = 0
+			}
+			handler := p.serveAPI(func(w http.ResponseWriter, r *http.Request) {
+				var workload UnschedulableReplicasRequest
+				if err := p.decodeRequest(r, &workload); err != nil {
+					writeError(w, err)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(tt.method, "/unschedul", strings.NewReader(tt.body)))
+			if w.Code != tt.wantCode {
+				t.Errorf("serveAPI() code = %d, want %d, body %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if w.Code == http.StatusMethodNotAllowed && w.Header().Get("Allow") != http.MethodPost {
+				t.Errorf("serveAPI() Allow header = %q, want %q", w.Header().Get("Allow"), http.MethodPost)
+			}
+		})
+	}
+}
+
+func TestProbes(t *testing.T) {
+	p := &PredictorServer{}
+
+	w := httptest.NewRecorder()
+	p.Healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Healthz() code = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w = httptest.NewRecorder()
+	p.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Readyz() code = %d before caches synced, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestRun(t *testing.T) {
+	// two servers could run side by side, as they do not share a mux
+	servers := []*PredictorServer{newTestServer(), newTestServer()}
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, len(servers))
+	for _, p := range servers {
+		p := p
+		go func() {
+			errCh <- p.Run(ctx)
+		}()
+	}
+
+	cancel()
+	for range servers {
+		select {
+		case err := <-errCh:
+			if err != nil {
+				t.Errorf("Run() error = %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run() did not return after the context was cancelled")
+		}
+	}
+}
+
+// BenchmarkMaxAcceptableReplicas predicts with the default plugins against 10k nodes running 20 pods each.
+// Every prediction is expected to take less than latencyTarget, including the rebuild of a node updated
+// in between and the spread of a workload over the zones, and the benchmark fails otherwise. With -short,
+// e.g. on a shared CI runner, the latency is only reported as ms/prediction.
+func BenchmarkMaxAcceptableReplicas(b *testing.B) {
+	const (
+		nodeCount   = 10000
+		podsPerNode = 20
+	)
+	p := newTestServer()
+	p.synced = 1
+	var err error
+	config := NewDefaultConfiguration()
+	p.framework, err = framework.NewFramework(NewRegistry(), config.Plugins, config.PluginConfig, p)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	nodes := make([]*corev1.Node, 0, nodeCount)
+	for i := 0; i < nodeCount; i++ {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("node-%05d", i),
+				Labels: map[string]string{
+					corev1.LabelHostname:           fmt.Sprintf("node-%05d", i),
+					corev1.LabelTopologyZone:       fmt.Sprintf("zone-%d", i%10),
+					corev1.LabelInstanceTypeStable: "standard",
+				},
+			},
+			Status: corev1.NodeStatus{
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("32"),
+					corev1.ResourceMemory: resource.MustParse("128Gi"),
+					corev1.ResourcePods:   resource.MustParse("110"),
+				},
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			},
+		}
+		nodes = append(nodes, node)
+		p.cache.AddNode(node)
+		for j := 0; j < podsPerNode; j++ {
+			p.cache.AddPod(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      fmt.Sprintf("pod-%05d-%02d", i, j),
+					Labels:    map[string]string{"app": fmt.Sprintf("app-%d", j)},
+				},
+				Spec: corev1.PodSpec{
+					NodeName: node.Name,
+					Containers: []corev1.Container{{
+						Name: "app",
+						Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("500m"),
+							corev1.ResourceMemory: resource.MustParse("1Gi"),
+						}},
+					}},
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			})
+		}
+	}
+
+	// build all the NodeInfos ahead, as the informers would have done before serving
+	p.cache.Snapshot()
+
+	resources := `{"namespace": "default", "podLabels": {"app": "web"},
+		"resources": {"requests": {"cpu": "1", "memory": "2Gi"}}}`
+	spread := `{"namespace": "default", "podLabels": {"app": "web"},
+		"resources": {"requests": {"cpu": "1", "memory": "2Gi"}},
+		"topologySpreadConstraints": [{"maxSkew": 1, "topologyKey": "topology.kubernetes.io/zone",
+			"whenUnsatisfiable": "DoNotSchedule", "labelSelector": {"matchLabels": {"app": "web"}}}]}`
+	handler := p.Handler()
+	// the first run of a benchmark counts the topology domains, and is not held to the target
+	const latencyTarget = 10 * time.Millisecond
+	run := func(b *testing.B, predict func(i int)) {
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			predict(i)
+		}
+		elapsed := time.Since(start) / time.Duration(b.N)
+		b.ReportMetric(float64(elapsed)/float64(time.Millisecond), "ms/prediction")
+		if !testing.Short() && b.N > 1 && elapsed > latencyTarget {
+			b.Errorf("prediction took %v, want less than %v", elapsed, latencyTarget)
+		}
+	}
+	serve := func(b *testing.B, body string) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/accept", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			b.Fatalf("MaxAcceptableReplicas() code = %d, body %s", w.Code, w.Body.String())
+		}
+	}
+
+	b.Run("resources", func(b *testing.B) {
+		run(b, func(int) {
+			serve(b, resources)
+		})
+	})
+	b.Run("resources with a node updated", func(b *testing.B) {
+		run(b, func(i int) {
+			node := nodes[i%nodeCount].DeepCopy()
+			node.ResourceVersion = fmt.Sprint(i)
+			p.cache.AddNode(node)
+			serve(b, resources)
+		})
+	})
+	// spread constraints sum the pods of every zone counted by the snapshot, instead of walking every pod
+	b.Run("topology spread", func(b *testing.B) {
+		run(b, func(int) {
+			serve(b, spread)
+		})
+	})
+	b.Run("topology spread with a pod added", func(b *testing.B) {
+		run(b, func(i int) {
+			node := nodes[i%nodeCount]
+			p.cache.AddPod(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      fmt.Sprintf("web-%d", i),
+					Labels:    map[string]string{"app": "web"},
+				},
+				Spec:   corev1.PodSpec{NodeName: node.Name},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			})
+			serve(b, spread)
+		})
+	})
+}
+
+func TestPreemption(t *testing.T) {
+	p := newTestServer()
+	p.synced = 1
+	p.cache.AddNode(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:  resource.MustParse("4"),
+			corev1.ResourcePods: resource.MustParse("110"),
+		}},
+	})
+	newPod := func(name, cpu string, priority int32) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: corev1.PodSpec{
+				NodeName: "node-1",
+				Priority: &priority,
+				Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+				}}},
+			},
+		}
+	}
+	p.cache.AddPod(newPod("batch", "2", 0))
+	p.cache.AddPod(newPod("system", "1", 1000))
+	if err := p.factory.Scheduling().V1().PriorityClasses().Informer().GetIndexer().Add(&schedulingv1.PriorityClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "high"},
+		Value:      100,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	p.framework, err = framework.NewFramework(plugins.NewInTreeRegistry(), framework.Plugins{
+		Estimate: []string{plugins.NodeResourcesFitName},
+	}, nil, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		wantCode     int
+		wantReplicas int64
+		// wantPreemption is the replicas with preemption, or -1 if they are not predicted
+		wantPreemption int64
+	}{
+		{
+			name:           "without priority",
+			body:           `{"resources": {"requests": {"cpu": "1"}}}`,
+			wantCode:       http.StatusOK,
+			wantReplicas:   1,
+			wantPreemption: -1,
+		},
+		{
+			name:           "priority class",
+			body:           `{"resources": {"requests": {"cpu": "1"}}, "priorityClassName": "high"}`,
+			wantCode:       http.StatusOK,
+			wantReplicas:   1,
+			wantPreemption: 3,
+		},
+		{
+			name:           "no pod of lower priority",
+			body:           `{"resources": {"requests": {"cpu": "1"}}, "priority": 0}`,
+			wantCode:       http.StatusOK,
+			wantReplicas:   1,
+			wantPreemption: 1,
+		},
+		{
+			name:           "never preempt",
+			body:           `{"resources": {"requests": {"cpu": "1"}}, "priority": 100, "preemptionPolicy": "Never"}`,
+			wantCode:       http.StatusOK,
+			wantReplicas:   1,
+			wantPreemption: -1,
+		},
+		{
+			name:     "priority mismatching the priority class",
+			body:     `{"resources": {"requests": {"cpu": "1"}}, "priorityClassName": "high", "priority": 5}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown priority class",
+			body:     `{"resources": {"requests": {"cpu": "1"}}, "priorityClassName": "missing"}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/accept", strings.NewReader(tt.body))
+			r.Header.Set("Accept", contentTypeJSON)
+			p.Handler().ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Fatalf("/accept code = %d, want %d, body %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var got AcceptableReplicas
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			preemption := int64(-1)
+			if got.WithPreemption != nil {
+				preemption = got.WithPreemption.MaxAcceptableReplicas
+			}
+			if got.MaxAcceptableReplicas != tt.wantReplicas || preemption != tt.wantPreemption {
+				t.Errorf("/accept replicas = %d and %d with preemption, want %d and %d",
+					got.MaxAcceptableReplicas, preemption, tt.wantReplicas, tt.wantPreemption)
+			}
+		})
+	}
+}
+
+func TestLimitRanges(t *testing.T) {
+	p := newTestServer()
+	p.synced = 1
+	p.cache.AddNode(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("4"),
+			corev1.ResourceMemory: resource.MustParse("64Gi"),
+			corev1.ResourcePods:   resource.MustParse("110"),
+		}},
+	})
+	if err := p.factory.Core().V1().LimitRanges().Informer().GetIndexer().Add(&corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "defaults"},
+		Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+			Type:           corev1.LimitTypeContainer,
+			Default:        corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("4Gi")},
+			DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+		}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	p.framework, err = framework.NewFramework(plugins.NewInTreeRegistry(), framework.Plugins{
+		Estimate: []string{plugins.NodeResourcesFitName},
+	}, nil, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		wantReplicas int64
+	}{
+		{
+			name:         "best effort without a limit range",
+			body:         `{"namespace": "other"}`,
+			wantReplicas: 110,
+		},
+		{
+			name:         "requests defaulted by the limit range",
+			body:         `{"namespace": "default"}`,
+			wantReplicas: 8,
+		},
+		{
+			name:         "declared requests are kept",
+			body:         `{"namespace": "default", "resources": {"requests": {"cpu": "2"}}}`,
+			wantReplicas: 2,
+		},
+		{
+			name:         "requests defaulted to the limits",
+			body:         `{"namespace": "other", "resources": {"limits": {"cpu": "1500m"}}}`,
+			wantReplicas: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/accept", strings.NewReader(tt.body))
+			r.Header.Set("Accept", contentTypeJSON)
+			p.Handler().ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("/accept code = %d, want %d, body %s", w.Code, http.StatusOK, w.Body.String())
+			}
+			var got AcceptableReplicas
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.MaxAcceptableReplicas != tt.wantReplicas {
+				t.Errorf("/accept replicas = %d, want %d", got.MaxAcceptableReplicas, tt.wantReplicas)
+			}
+		})
+	}
+}
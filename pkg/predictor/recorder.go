@@ -0,0 +1,263 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	coordinationlisters "k8s.io/client-go/listers/coordination/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	schedulinglisters "k8s.io/client-go/listers/scheduling/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+const (
+	recordFilePrefix = "record-"
+	recordFileSuffix = ".json.gz"
+	// recordQueueLength bounds the records waiting to be written. Records are dropped when it is full,
+	// so that a slow disk never slows down the predictions.
+	recordQueueLength = 16
+)
+
+// PredictionRecord is a prediction together with the objects it was made against.
+type PredictionRecord struct {
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Time       metav1.Time `json:"time"`
+
+	Request MaxAcceptableReplicasRequest `json:"request"`
+	// Result is the response of the prediction, or Error if it failed.
+	Result *AcceptableReplicas `json:"result,omitempty"`
+	Error  string              `json:"error,omitempty"`
+
+	// Nodes and Pods are the snapshot the prediction was made against, and the others are the objects
+	// read by the plugins.
+	Nodes []*corev1.Node `json:"nodes,omitempty"`
+	Pods  []*corev1.Pod  `json:"pods,omitempty"`
+	// AssumedReplicas are the replicas assumed to be placed on the nodes of the snapshot, e.g. those reserved.
+	AssumedReplicas []RecordedAssumedReplicas `json:"assumedReplicas,omitempty"`
+	Namespaces      []*corev1.Namespace       `json:"namespaces,omitempty"`
+	ResourceQuotas  []*corev1.ResourceQuota   `json:"resourceQuotas,omitempty"`
+	LimitRanges     []*corev1.LimitRange      `json:"limitRanges,omitempty"`
+	Leases          []*coordinationv1.Lease   `json:"leases,omitempty"`
+	// PriorityClass is the PriorityClass of the request, if it names one.
+	PriorityClass *schedulingv1.PriorityClass `json:"priorityClass,omitempty"`
+}
+
+// RecordedAssumedReplicas are the replicas assumed to be placed on a node of a recorded snapshot.
+type RecordedAssumedReplicas struct {
+	NodeName string `json:"nodeName"`
+	framework.AssumedReplicas
+}
+
+// recorder writes the predictions with their snapshots to a directory, keeping the latest maxFiles records.
+type recorder struct {
+	dir      string
+	maxFiles int
+	seq      uint64
+
+	namespaceLister     corelisters.NamespaceLister
+	quotaLister         corelisters.ResourceQuotaLister
+	limitRangeLister    corelisters.LimitRangeLister
+	leaseLister         coordinationlisters.LeaseLister
+	priorityClassLister schedulinglisters.PriorityClassLister
+
+	queue chan *pendingRecord
+}
+
+// pendingRecord is a record waiting to be written. The result is converted by the writer.
+type pendingRecord struct {
+	record   *PredictionRecord
+	snapshot *framework.Snapshot
+	result   *framework.Result
+}
+
+// newRecorder returns a recorder writing to dir. The informers of the recorded objects are requested from
+// the factory, so that it must be called before the factory is started.
+func newRecorder(dir string, maxFiles int, factory informers.SharedInformerFactory) (*recorder, error) {
+	if maxFiles <= 0 {
+		return nil, fmt.Errorf("max files of records must be positive")
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("error of create record directory : %v", err)
+	}
+	return &recorder{
+		dir:                 dir,
+		maxFiles:            maxFiles,
+		namespaceLister:     factory.Core().V1().Namespaces().Lister(),
+		quotaLister:         factory.Core().V1().ResourceQuotas().Lister(),
+		limitRangeLister:    factory.Core().V1().LimitRanges().Lister(),
+		leaseLister:         factory.Coordination().V1().Leases().Lister(),
+		priorityClassLister: factory.Scheduling().V1().PriorityClasses().Lister(),
+		queue:               make(chan *pendingRecord, recordQueueLength),
+	}, nil
+}
+
+// run writes the queued records until stopCh is closed.
+func (r *recorder) run(stopCh <-chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			return
+		case pending := <-r.queue:
+			if err := r.write(pending); err != nil {
+				klog.Errorf("error of write prediction record : %v", err)
+			}
+		}
+	}
+}
+
+// record queues the prediction to be written. The objects read by the plugins are listed at once,
+// while the snapshot, which is immutable, is serialized later by the writer.
+func (r *recorder) record(require *MaxAcceptableReplicasRequest, snapshot *framework.Snapshot, result *framework.Result, err error) {
+	record := &PredictionRecord{
+		APIVersion: PredictorAPIVersion,
+		Kind:       "PredictionRecord",
+		Time:       metav1.Now(),
+		Request:    *require,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	var listErr error
+	if record.Namespaces, listErr = r.namespaceLister.List(labels.Everything()); listErr != nil {
+		klog.Errorf("error of list namespaces to record : %v", listErr)
+	}
+	if require.Namespace != "" {
+		if record.ResourceQuotas, listErr = r.quotaLister.ResourceQuotas(require.Namespace).List(labels.Everything()); listErr != nil {
+			klog.Errorf("error of list resource quotas to record : %v", listErr)
+		}
+		if record.LimitRanges, listErr = r.limitRangeLister.LimitRanges(require.Namespace).List(labels.Everything()); listErr != nil {
+			klog.Errorf("error of list limit ranges to record : %v", listErr)
+		}
+	}
+	if record.Leases, listErr = r.leaseLister.Leases(corev1.NamespaceNodeLease).List(labels.Everything()); listErr != nil {
+		klog.Errorf("error of list leases to record : %v", listErr)
+	}
+	if require.PriorityClassName != "" {
+		if record.PriorityClass, listErr = r.priorityClassLister.Get(require.PriorityClassName); listErr != nil {
+			klog.Errorf("error of get priority class to record : %v", listErr)
+		}
+	}
+
+	select {
+	case r.queue <- &pendingRecord{record: record, snapshot: snapshot, result: result}:
+	default:
+		klog.Warningf("drop prediction record, as %d records are waiting to be written", recordQueueLength)
+	}
+}
+
+// write writes the record to a new file and removes the oldest files beyond maxFiles.
+func (r *recorder) write(pending *pendingRecord) error {
+	record := pending.record
+	if pending.result != nil {
+		record.Result = newAcceptableReplicas(pending.result)
+	}
+	for _, nodeInfo := range pending.snapshot.List() {
+		record.Nodes = append(record.Nodes, nodeInfo.Node())
+		record.Pods = append(record.Pods, nodeInfo.Pods()...)
+		for _, assumed := range nodeInfo.AssumedReplicas() {
+			record.AssumedReplicas = append(record.AssumedReplicas,
+				RecordedAssumedReplicas{NodeName: nodeInfo.Node().Name, AssumedReplicas: assumed})
+		}
+	}
+
+	// the names sort in the order the records are made
+	r.seq++
+	name := fmt.Sprintf("%s%s-%06d%s", recordFilePrefix, record.Time.UTC().Format("20060102T150405.000000000"),
+		r.seq, recordFileSuffix)
+	file, err := os.OpenFile(filepath.Join(r.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(file)
+	if err = json.NewEncoder(zw).Encode(record); err != nil {
+		file.Close()
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	klog.V(5).Infof("prediction is recorded in %s", name)
+	return r.rotate()
+}
+
+// rotate removes the oldest records beyond maxFiles.
+func (r *recorder) rotate() error {
+	names, err := listRecordFiles(r.dir)
+	if err != nil {
+		return err
+	}
+	for len(names) > r.maxFiles {
+		if err = os.Remove(names[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// listRecordFiles returns the paths of the records in dir, from the oldest to the latest.
+func listRecordFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasPrefix(info.Name(), recordFilePrefix) && strings.HasSuffix(info.Name(), recordFileSuffix) {
+			names = append(names, filepath.Join(dir, info.Name()))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readRecordFile reads a record written by the recorder.
+func readRecordFile(path string) (*PredictionRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("error of read record %s : %v", path, err)
+	}
+	var record PredictionRecord
+	if err = json.NewDecoder(zr).Decode(&record); err != nil {
+		return nil, fmt.Errorf("error of decode record %s : %v", path, err)
+	}
+	return &record, nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	p := newTestServer()
+	var err error
+	p.recorder, err = newRecorder(filepath.Join(dir, "records"), 2, p.factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := NewDefaultConfiguration()
+	p.framework, err = framework.NewFramework(NewRegistry(), config.Plugins, config.PluginConfig, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renewTime := metav1.NewMicroTime(time.Now())
+	for _, name := range []string{"node-1", "node-2"} {
+		p.cache.AddNode(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{Unschedulable: name == "node-2"},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:  resource.MustParse("4"),
+				corev1.ResourcePods: resource.MustParse("110"),
+			}},
+		})
+		if err = p.factory.Coordination().V1().Leases().Informer().GetIndexer().Add(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: corev1.NamespaceNodeLease, Name: name},
+			Spec:       coordinationv1.LeaseSpec{RenewTime: &renewTime},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.cache.AddPod(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-1"},
+		Spec: corev1.PodSpec{
+			NodeName:   "node-1",
+			Containers: []corev1.Container{{Name: "app", Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}}}},
+		},
+	})
+
+	for _, cpu := range []string{"500m", "1", "2"} {
+		require := &MaxAcceptableReplicasRequest{}
+		require.Resources.Requests = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}
+		if _, err = p.predict(context.TODO(), require, p.cache.Snapshot()); err != nil {
+			t.Fatal(err)
+		}
+		if err = p.recorder.write(<-p.recorder.queue); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := listRecordFiles(filepath.Join(dir, "records"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("records = %v, want the latest 2", files)
+	}
+	record, err := readRecordFile(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(record.Nodes) != 2 || len(record.Pods) != 1 || len(record.Leases) != 2 || record.Result.MaxAcceptableReplicas != 1 {
+		t.Errorf("record = %+v, want the snapshot and the result of 1 replica", record)
+	}
+
+	// without filters, the cordoned node is predicted as well
+	configFile := filepath.Join(dir, "config.yaml")
+	if err = ioutil.WriteFile(configFile, []byte("plugins:\n  filter: []\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		configFile string
+		wantDiff   bool
+	}{
+		{
+			name: "same plugins",
+		},
+		{
+			name:       "changed plugins",
+			configFile: configFile,
+			wantDiff:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := Replay(context.TODO(), ReplayOptions{RecordPaths: []string{filepath.Join(dir, "records")}, ConfigFile: tt.configFile}, NewRegistry())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 2 {
+				t.Fatalf("Replay() results = %d, want 2", len(results))
+			}
+			for _, result := range results {
+				if (result.Diff != "") != tt.wantDiff {
+					t.Errorf("Replay() %s diff = %q, want diff %v", result.Path, result.Diff, tt.wantDiff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+	"github.com/clusternet/sample-controller/pkg/predictor/plugins"
+)
+
+// NewRegistry return a plugin registry with in-tree plugins and our own plugins
+func NewRegistry() framework.Registry {
+	myRegistry := plugins.NewInTreeRegistry()
+
+	// TODO: we can add our own plugins here, and enable them in the predictor configuration
+	myRegistry[TKECloudHSSDName] = NewTKECloudHSSD // here is an example
+
+	return myRegistry
+}
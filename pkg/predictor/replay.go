@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/diff"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+// ReplayOptions is options for replaying recorded predictions against the current plugins.
+type ReplayOptions struct {
+	// RecordPaths are the records written by the recorder, or the directories of them.
+	RecordPaths []string
+	// ConfigFile is the path of the predictor configuration file, which enables and configures plugins.
+	ConfigFile string
+}
+
+// ReplayResult is the outcome of replaying a record.
+type ReplayResult struct {
+	Path string
+	// Diff is the difference of the replayed prediction from the recorded one, empty when they are the same.
+	Diff string
+}
+
+// replayedPrediction is the part of a record compared by replays.
+type replayedPrediction struct {
+	Result *AcceptableReplicas
+	Error  string
+}
+
+// Replay predicts the requests of the records again against the objects recorded with them,
+// and compares the results with the recorded ones.
+func Replay(ctx context.Context, options ReplayOptions, registry framework.Registry) ([]ReplayResult, error) {
+	config, err := LoadConfiguration(options.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, path := range options.RecordPaths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error of load records : %v", err)
+		}
+		if !info.IsDir() {
+			paths = append(paths, path)
+			continue
+		}
+		files, err := listRecordFiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("error of load records under %s : %v", path, err)
+		}
+		paths = append(paths, files...)
+	}
+
+	results := make([]ReplayResult, 0, len(paths))
+	for _, path := range paths {
+		record, err := readRecordFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// records without their PriorityClass are replayed with the priority resolved from it
+		if record.PriorityClass == nil {
+			record.Request.PriorityClassName = ""
+		}
+		replayed := replayedPrediction{}
+		replayed.Result, err = predictObjects(ctx, config, registry, recordedObjects(record), recordedAssumedReplicas(record), &record.Request)
+		if err != nil {
+			replayed.Error = err.Error()
+		}
+		recorded := replayedPrediction{Result: record.Result, Error: record.Error}
+		result := ReplayResult{Path: path}
+		if !reflect.DeepEqual(recorded, replayed) {
+			result.Diff = diff.ObjectReflectDiff(recorded, replayed)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// recordedAssumedReplicas returns the assumed replicas of the record, keyed by node name.
+func recordedAssumedReplicas(record *PredictionRecord) map[string][]framework.AssumedReplicas {
+	assumed := make(map[string][]framework.AssumedReplicas)
+	for _, recorded := range record.AssumedReplicas {
+		assumed[recorded.NodeName] = append(assumed[recorded.NodeName], recorded.AssumedReplicas)
+	}
+	return assumed
+}
+
+// recordedObjects returns the objects of the record. The leases are renewed as much later as the record
+// is replayed, so that their ages are the same as when the prediction was made.
+func recordedObjects(record *PredictionRecord) []runtime.Object {
+	var objects []runtime.Object
+	for _, node := range record.Nodes {
+		objects = append(objects, node)
+	}
+	for _, pod := range record.Pods {
+		objects = append(objects, pod)
+	}
+	for _, namespace := range record.Namespaces {
+		objects = append(objects, namespace)
+	}
+	for _, quota := range record.ResourceQuotas {
+		objects = append(objects, quota)
+	}
+	for _, limitRange := range record.LimitRanges {
+		objects = append(objects, limitRange)
+	}
+	elapsed := time.Since(record.Time.Time)
+	for _, lease := range record.Leases {
+		if lease.Spec.RenewTime != nil {
+			renewTime := metav1.NewMicroTime(lease.Spec.RenewTime.Add(elapsed))
+			lease.Spec.RenewTime = &renewTime
+		}
+		objects = append(objects, lease)
+	}
+	if record.PriorityClass != nil {
+		objects = append(objects, record.PriorityClass)
+	}
+	return objects
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+	"github.com/clusternet/sample-controller/pkg/predictor/metrics"
+)
+
+// reservationExpirePeriod is how often the expired reservations are removed. Expired reservations are
+// never seen by predictions, so it only bounds how long they are reported by the metrics.
+const reservationExpirePeriod = 5 * time.Second
+
+// reservations holds the capacity reserved by predictions, keyed by the ids given by callers.
+type reservations struct {
+	// cluster is the cluster the replicas are reserved in, which labels the metrics.
+	cluster string
+	ttl     time.Duration
+	// maxReplicas is the most replicas a reservation holds, so that a single caller could not take up
+	// the whole cluster. There is no limit when it is 0.
+	maxReplicas int64
+	clock       clock.PassiveClock
+
+	// predictMu serializes the predictions making reservations, so that each of them sees the
+	// reservations of the others.
+	predictMu sync.Mutex
+
+	mu    sync.Mutex
+	items map[string]*reservation
+}
+
+// reservation is the replicas of a workload reserved on the nodes.
+type reservation struct {
+	require *MaxAcceptableReplicasRequest
+	// selector selects the pods of the workload taking up the reservation. It is nil if the workload
+	// has no pod labels.
+	selector labels.Selector
+	// placement is the reserved replicas of every node, which are decreased as the pods are bound.
+	placement      map[string]int64
+	expirationTime time.Time
+}
+
+// newReservations returns the reservations of the cluster expiring after ttl, each holding at most maxReplicas.
+func newReservations(cluster string, ttl time.Duration, maxReplicas int64, clock clock.PassiveClock) *reservations {
+	return &reservations{
+		cluster:     cluster,
+		ttl:         ttl,
+		maxReplicas: maxReplicas,
+		clock:       clock,
+		items:       make(map[string]*reservation),
+	}
+}
+
+// run removes the expired reservations until stopCh is closed.
+func (r *reservations) run(stopCh <-chan struct{}) {
+	wait.Until(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.expire()
+	}, reservationExpirePeriod, stopCh)
+}
+
+// reserve reserves the placement under the id, replacing the reservation of the same id. A placement of
+// more than maxReplicas is spread again with only maxReplicas.
+func (r *reservations) reserve(id string, require *MaxAcceptableReplicasRequest, placement map[string]int64) *Reservation {
+	var replicas int64
+	for _, nodeReplicas := range placement {
+		replicas += nodeReplicas
+	}
+	if r.maxReplicas > 0 && replicas > r.maxReplicas {
+		klog.V(4).Infof("reserve %d of the %d replicas predicted for %q", r.maxReplicas, replicas, id)
+		placement = spreadReplicas(r.maxReplicas, placement)
+	}
+	res := &reservation{
+		require:        require,
+		placement:      placement,
+		expirationTime: r.clock.Now().Add(r.ttl),
+	}
+	if len(require.PodLabels) != 0 {
+		res.selector = labels.SelectorFromSet(require.PodLabels)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// a prediction without any replica reserves nothing, but still replaces the earlier reservation
+	if len(placement) == 0 {
+		delete(r.items, id)
+	} else {
+		r.items[id] = res
+	}
+	r.expire()
+	klog.V(4).Infof("reserve %d replicas for %q until %v", res.replicas(), id, res.expirationTime)
+	return &Reservation{ID: id, Replicas: res.replicas(), ExpirationTime: metav1.NewTime(res.expirationTime)}
+}
+
+// release releases the reservation of the id, and returns whether it was active.
+func (r *reservations) release(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire()
+	_, ok := r.items[id]
+	if ok {
+		delete(r.items, id)
+		r.updateMetrics()
+		klog.V(4).Infof("release reservation %q", id)
+	}
+	return ok
+}
+
+// assume returns a snapshot of the nodes with the reserved replicas assumed to be placed on them,
+// except for the reservations of the excluded ids.
+func (r *reservations) assume(snapshot *framework.Snapshot, excluded sets.String) *framework.Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire()
+
+	assumed := make(map[string][]framework.AssumedReplicas)
+	for id, res := range r.items {
+		if excluded.Has(id) {
+			continue
+		}
+		pod := newAssumedPod(res.require, "reservation-"+id)
+		for name, replicas := range res.placement {
+			assumed[name] = append(assumed[name], framework.AssumedReplicas{Pod: pod, Replicas: replicas})
+		}
+	}
+	return snapshot.WithAssumedReplicas(assumed)
+}
+
+// podEventHandler returns the handler taking up the reservations with the pods bound to nodes.
+func (r *reservations) podEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*corev1.Pod); ok && pod.Spec.NodeName != "" {
+				r.bind(pod)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, ok := oldObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			if pod, ok := newObj.(*corev1.Pod); ok && oldPod.Spec.NodeName == "" && pod.Spec.NodeName != "" {
+				r.bind(pod)
+			}
+		},
+	}
+}
+
+// bind takes up a replica of the earliest expiring reservation matching the pod, on the node of the pod
+// if it is reserved there, or otherwise on the node with the most reserved replicas.
+// A reservation is removed once all its replicas are taken up.
+func (r *reservations) bind(pod *corev1.Pod) {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire()
+
+	var matchedID string
+	var matched *reservation
+	for id, res := range r.items {
+		if res.selector == nil || res.require.Namespace != pod.Namespace || !res.selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if matched == nil || res.expirationTime.Before(matched.expirationTime) ||
+			res.expirationTime.Equal(matched.expirationTime) && id < matchedID {
+			matchedID, matched = id, res
+		}
+	}
+	if matched == nil {
+		return
+	}
+
+	nodeName := pod.Spec.NodeName
+	if matched.placement[nodeName] <= 0 {
+		names := make([]string, 0, len(matched.placement))
+		for name := range matched.placement {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		nodeName = ""
+		for _, name := range names {
+			if nodeName == "" || matched.placement[name] > matched.placement[nodeName] {
+				nodeName = name
+			}
+		}
+	}
+	if matched.placement[nodeName]--; matched.placement[nodeName] <= 0 {
+		delete(matched.placement, nodeName)
+	}
+	klog.V(5).Infof("pod %s/%s takes up a replica of reservation %q on node %s", pod.Namespace, pod.Name, matchedID, nodeName)
+	if len(matched.placement) == 0 {
+		delete(r.items, matchedID)
+		klog.V(4).Infof("reservation %q is taken up by the pods", matchedID)
+	}
+	r.updateMetrics()
+}
+
+// expire removes the expired reservations. It must be called with mu held.
+func (r *reservations) expire() {
+	now := r.clock.Now()
+	for id, res := range r.items {
+		if !now.Before(res.expirationTime) {
+			delete(r.items, id)
+			klog.V(4).Infof("reservation %q expired", id)
+		}
+	}
+	r.updateMetrics()
+}
+
+// updateMetrics reports the active reservations. It must be called with mu held.
+func (r *reservations) updateMetrics() {
+	var replicas int64
+	for _, res := range r.items {
+		replicas += res.replicas()
+	}
+	metrics.Reservations.WithLabelValues(r.cluster).Set(float64(len(r.items)))
+	metrics.ReservedReplicas.WithLabelValues(r.cluster).Set(float64(replicas))
+}
+
+// replicas returns the replicas still reserved.
+func (res *reservation) replicas() int64 {
+	var replicas int64
+	for _, nodeReplicas := range res.placement {
+		replicas += nodeReplicas
+	}
+	return replicas
+}
+
+// Release is a http handler releasing a reservation before it expires.
+func (p *PredictorServer) Release(w http.ResponseWriter, r *http.Request) {
+	var release ReleaseRequest
+	if err := p.decodeRequest(r, &release); err != nil {
+		writeError(w, err)
+		return
+	}
+	if release.ID == "" {
+		writeError(w, apierrors.NewBadRequest("id of the reservation is required"))
+		return
+	}
+	if p.reservations == nil {
+		writeError(w, errReservationsDisabled)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeJSON)
+	if err := json.NewEncoder(w).Encode(ReleaseResponse{
+		ID:       release.ID,
+		Released: p.reservations.release(release.ID),
+	}); err != nil {
+		klog.Error(err)
+	}
+}
+
+// errReservationsDisabled is returned to requests making or releasing reservations when they are disabled.
+var errReservationsDisabled = apierrors.NewBadRequest("reservations are disabled, enable them with --reservation-ttl")
@@ -0,0 +1,253 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+	"github.com/clusternet/sample-controller/pkg/predictor/plugins"
+)
+
+func TestReservations(t *testing.T) {
+	p := newTestServer()
+	p.synced = 1
+	for _, name := range []string{"node-1", "node-2"} {
+		p.cache.AddNode(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:  resource.MustParse("4"),
+				corev1.ResourcePods: resource.MustParse("110"),
+			}},
+		})
+	}
+	var err error
+	p.framework, err = framework.NewFramework(plugins.NewInTreeRegistry(), framework.Plugins{
+		Estimate: []string{plugins.NodeResourcesFitName},
+	}, nil, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	p.reservations = newReservations("", time.Minute, 10, fakeClock)
+	handler := p.reservations.podEventHandler()
+
+	web := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-1", Labels: map[string]string{"app": "web"}},
+		Spec:       corev1.PodSpec{NodeName: "node-2"},
+	}
+	tests := []struct {
+		name string
+		// prepare changes the state before the request
+		prepare      func()
+		path         string
+		body         string
+		wantCode     int
+		wantReplicas int64
+		wantReserved int64
+		// plain answers the replicas as a plain integer
+		plain bool
+	}{
+		{
+			name:         "reserve",
+			path:         "/accept",
+			body:         `{"resources": {"requests": {"cpu": "1"}}, "namespace": "default", "podLabels": {"app": "web"}, "reservationID": "web"}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: 8,
+			wantReserved: 8,
+		},
+		{
+			name:         "capacity reserved by others",
+			path:         "/accept",
+			body:         `{"resources": {"requests": {"cpu": "1"}}}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: 0,
+		},
+		{
+			name:         "replace the reservation",
+			path:         "/accept",
+			body:         `{"resources": {"requests": {"cpu": "2"}}, "namespace": "default", "podLabels": {"app": "web"}, "reservationID": "web"}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: 4,
+			wantReserved: 4,
+		},
+		{
+			name: "taken up by bound pods",
+			prepare: func() {
+				handler.OnAdd(web)
+				pending := web.DeepCopy()
+				pending.Name, pending.Spec.NodeName = "web-2", ""
+				bound := pending.DeepCopy()
+				bound.Spec.NodeName = "node-1"
+				handler.OnUpdate(pending, bound)
+				// neither pods of other workloads nor updates of bound pods take up replicas
+				other := web.DeepCopy()
+				other.Name, other.Labels = "db-1", map[string]string{"app": "db"}
+				handler.OnAdd(other)
+				handler.OnUpdate(web, web)
+			},
+			path:         "/accept",
+			body:         `{"resources": {"requests": {"cpu": "2"}}}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: 2,
+		},
+		{
+			name:     "release",
+			path:     "/release",
+			body:     `{"id": "web"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:         "reserve in a batch",
+			path:         "/accept-batch",
+			body:         `{"items": [{"resources": {"requests": {"cpu": "1"}}, "reservationID": "batch"}]}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: 8,
+			wantReserved: 8,
+		},
+		{
+			name:         "reserve the desired replicas in a batch",
+			path:         "/accept-batch",
+			body:         `{"items": [{"replicas": 3, "resources": {"requests": {"cpu": "1"}}, "reservationID": "batch"}]}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: 3,
+			wantReserved: 3,
+		},
+		{
+			name:         "expired",
+			prepare:      func() { fakeClock.Step(time.Minute) },
+			path:         "/accept",
+			body:         `{"resources": {"requests": {"cpu": "1"}}}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: 8,
+		},
+		{
+			name:         "reserve at most the max replicas",
+			path:         "/accept",
+			body:         `{"resources": {"requests": {"cpu": "500m"}}, "reservationID": "small"}`,
+			wantCode:     http.StatusOK,
+			wantReplicas: 10,
+			wantReserved: 10,
+		},
+		{
+			name:         "answer the reserved replicas in plain text",
+			path:         "/accept",
+			body:         `{"resources": {"requests": {"cpu": "500m"}}, "reservationID": "small"}`,
+			plain:        true,
+			wantCode:     http.StatusOK,
+			wantReplicas: 10,
+		},
+		{
+			name:     "capacity beyond the max replicas",
+			path:     "/accept",
+			body:     `{"resources": {"requests": {"cpu": "1"}}}`,
+			wantCode: http.StatusOK,
+			// 5 replicas of 500m are reserved on every node
+			wantReplicas: 2,
+		},
+		{
+			name:     "release without id",
+			path:     "/release",
+			body:     `{}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.prepare != nil {
+				tt.prepare()
+			}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if !tt.plain {
+				r.Header.Set("Accept", contentTypeJSON)
+			}
+			p.Handler().ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Fatalf("%s code = %d, want %d, body %s", tt.path, w.Code, tt.wantCode, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			if tt.plain {
+				if w.Body.String() != strconv.FormatInt(tt.wantReplicas, 10) {
+					t.Errorf("%s replicas = %s, want %d", tt.path, w.Body.String(), tt.wantReplicas)
+				}
+				return
+			}
+
+			var got *AcceptableReplicas
+			switch tt.path {
+			case "/accept":
+				got = &AcceptableReplicas{}
+				if err := json.Unmarshal(w.Body.Bytes(), got); err != nil {
+					t.Fatal(err)
+				}
+			case "/accept-batch":
+				var resp BatchAcceptableReplicas
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				got = resp.Items[0].Result
+			case "/release":
+				var resp ReleaseResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				// two of the four replicas are taken up by the pods, and the others are released
+				if !resp.Released || p.reservations.release("web") {
+					t.Errorf("/release released = %v, want it released once", resp.Released)
+				}
+				return
+			}
+			if got.MaxAcceptableReplicas != tt.wantReplicas {
+				t.Errorf("%s replicas = %d, want %d", tt.path, got.MaxAcceptableReplicas, tt.wantReplicas)
+			}
+			var reserved int64
+			if got.Reservation != nil {
+				reserved = got.Reservation.Replicas
+			}
+			if reserved != tt.wantReserved {
+				t.Errorf("%s reserved replicas = %d, want %d", tt.path, reserved, tt.wantReserved)
+			}
+			// the caller never commits more replicas than are reserved
+			if got.Reservation != nil && got.MaxAcceptableReplicas != got.Reservation.Replicas {
+				t.Errorf("%s replicas = %d, want the %d reserved", tt.path, got.MaxAcceptableReplicas, got.Reservation.Replicas)
+			}
+		})
+	}
+}
+
+func TestReservationsDisabled(t *testing.T) {
+	p := newTestServer()
+	p.synced = 1
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/accept",
+		strings.NewReader(`{"resources": {"requests": {"cpu": "1"}}, "reservationID": "web"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("/accept code = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
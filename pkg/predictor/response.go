@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+const (
+	contentTypeJSON = "application/json"
+	contentTypeText = "text/plain"
+)
+
+// wantsJSON checks whether the Accept header prefers json to plain text.
+// Wildcards are not taken into account, so that callers not asking for json explicitly
+// keep receiving a plain integer.
+func wantsJSON(accept string) bool {
+	var jsonQ, textQ float64 = -1, -1
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case contentTypeJSON:
+			jsonQ = q
+		case contentTypeText:
+			textQ = q
+		}
+	}
+	return jsonQ > 0 && jsonQ >= textQ
+}
+
+// newAcceptableReplicas converts the result of the framework to the structured response.
+func newAcceptableReplicas(result *framework.Result) *AcceptableReplicas {
+	resp := &AcceptableReplicas{
+		APIVersion:            PredictorAPIVersion,
+		Kind:                  "AcceptableReplicas",
+		MaxAcceptableReplicas: result.Replicas,
+		Nodes:                 newNodeReplicas(result),
+		ClusterCap:            newClusterCap(result.Cap),
+	}
+	for name, status := range result.Rejections {
+		resp.RejectedNodes = append(resp.RejectedNodes, RejectedNode{
+			Name:    name,
+			Plugin:  status.Plugin(),
+			Reasons: status.Reasons(),
+		})
+	}
+	sort.Slice(resp.RejectedNodes, func(i, j int) bool {
+		return resp.RejectedNodes[i].Name < resp.RejectedNodes[j].Name
+	})
+	if result.Preemption != nil {
+		resp.WithPreemption = &PreemptionReplicas{
+			MaxAcceptableReplicas: result.Preemption.Replicas,
+			Nodes:                 newNodeReplicas(result.Preemption),
+			ClusterCap:            newClusterCap(result.Preemption.Cap),
+		}
+	}
+	return resp
+}
+
+// newNodeReplicas returns the replicas of every node of the result, sorted by node name.
+func newNodeReplicas(result *framework.Result) []NodeReplicas {
+	var nodes []NodeReplicas
+	for name, replicas := range result.NodeReplicas {
+		nodes = append(nodes, NodeReplicas{
+			Name:      name,
+			Replicas:  replicas,
+			LimitedBy: result.LimitedBy[name],
+		})
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes
+}
+
+func newClusterCap(cap *framework.CapResult) *ClusterCap {
+	if cap == nil {
+		return nil
+	}
+	return &ClusterCap{
+		Plugin:   cap.Plugin,
+		Replicas: cap.Replicas,
+		Reason:   cap.Reason,
+	}
+}
+
+// setReservation sets the reservation made for the request. The replicas answered are lowered to the
+// reserved ones, so that the caller never commits more replicas than are held for it.
+func setReservation(resp *AcceptableReplicas, reservation *Reservation) {
+	resp.Reservation = reservation
+	if reservation != nil && reservation.Replicas < resp.MaxAcceptableReplicas {
+		resp.MaxAcceptableReplicas = reservation.Replicas
+	}
+}
+
+// writeAcceptableReplicas writes the result as json or as a plain integer, according to the Accept header.
+// The reservation made for the request, if any, is only written in json, while the replicas are those
+// reserved in both.
+func writeAcceptableReplicas(w http.ResponseWriter, r *http.Request, result *framework.Result, reservation *Reservation) {
+	resp := newAcceptableReplicas(result)
+	setReservation(resp, reservation)
+	if !wantsJSON(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", contentTypeText)
+		if _, err := w.Write([]byte(strconv.FormatInt(resp.MaxAcceptableReplicas, 10))); err != nil {
+			klog.Error(err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeJSON)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		klog.Error(err)
+	}
+}
+
+// writeError writes the error as a json Status with the http code of the error.
+func writeError(w http.ResponseWriter, err *apierrors.StatusError) {
+	status := err.Status()
+	status.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(int(status.Code))
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		klog.Error(err)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+	"k8s.io/klog/v2"
+)
+
+// servingOptions are the tls settings of the predictor server.
+type servingOptions struct {
+	certFile          string
+	keyFile           string
+	requireClientCert bool
+}
+
+// newTLSConfig returns a tls config whose serving certificate and client CA are reloaded
+// when they change on disk. It returns nil if the server is not served over https.
+func newTLSConfig(options servingOptions, clientCA *dynamiccertificates.DynamicFileCAContent, stopCh <-chan struct{}) (*tls.Config, error) {
+	if options.certFile == "" {
+		return nil, nil
+	}
+
+	servingCert, err := dynamiccertificates.NewDynamicServingContentFromFiles("serving-cert", options.certFile, options.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error of load serving certificate : %v", err)
+	}
+
+	baseTLSConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	var caProvider dynamiccertificates.CAContentProvider
+	if clientCA != nil {
+		caProvider = clientCA
+		baseTLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if options.requireClientCert {
+			baseTLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	controller := dynamiccertificates.NewDynamicServingCertificateController(baseTLSConfig, caProvider, servingCert, nil, nil)
+	servingCert.AddListener(controller)
+	go servingCert.Run(1, stopCh)
+	if clientCA != nil {
+		clientCA.AddListener(controller)
+		go clientCA.Run(1, stopCh)
+	}
+	// prime the tls config, connections are refused until it is populated
+	if err = controller.RunOnce(); err != nil {
+		klog.Warningf("initial population of serving certificates failed: %v", err)
+	}
+	go controller.Run(1, stopCh)
+
+	baseTLSConfig.GetConfigForClient = controller.GetConfigForClient
+	return baseTLSConfig, nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/util/cert"
+)
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options PredictorOptions
+		wantErr bool
+	}{
+		{
+			name:    "plain http",
+			options: PredictorOptions{MaxRequestBytes: 1 << 20},
+		},
+		{
+			name:    "mutual tls",
+			options: PredictorOptions{MaxRequestBytes: 1 << 20, TLSCertFile: "tls.crt", TLSPrivateKeyFile: "tls.key", ClientCAFile: "ca.crt", RequireClientCert: true},
+		},
+		{
+			name:    "certificate without key",
+			options: PredictorOptions{MaxRequestBytes: 1 << 20, TLSCertFile: "tls.crt"},
+			wantErr: true,
+		},
+		{
+			name:    "client CA without serving certificate",
+			options: PredictorOptions{MaxRequestBytes: 1 << 20, ClientCAFile: "ca.crt"},
+			wantErr: true,
+		},
+		{
+			name:    "client certificate required without client CA",
+			options: PredictorOptions{MaxRequestBytes: 1 << 20, TLSCertFile: "tls.crt", TLSPrivateKeyFile: "tls.key", RequireClientCert: true},
+			wantErr: true,
+		},
+		{
+			name:    "no max request bytes",
+			options: PredictorOptions{},
+			wantErr: true,
+		},
+		{
+			name:    "negative max request bytes",
+			options: PredictorOptions{MaxRequestBytes: -1},
+			wantErr: true,
+		},
+		{
+			name:    "negative reservation max replicas",
+			options: PredictorOptions{MaxRequestBytes: 1 << 20, ReservationTTL: time.Minute, ReservationMaxReplicas: -1},
+			wantErr: true,
+		},
+		{
+			name:    "records without max files",
+			options: PredictorOptions{MaxRequestBytes: 1 << 20, RecordDir: "records"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.options.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	certPEM, keyPEM, err := cert.GenerateSelfSignedCertKey("127.0.0.1", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err = ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	tlsConfig, err := newTLSConfig(servingOptions{certFile: certFile, keyFile: keyFile}, nil, stopCh)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status code = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	predictorcache "github.com/clusternet/sample-controller/pkg/predictor/cache"
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+	"github.com/clusternet/sample-controller/pkg/predictor/plugins"
+)
+
+// SimulateOptions is options for simulating a prediction against a captured cluster state.
+type SimulateOptions struct {
+	// ObjectPaths are the yaml or json files, or the directories of them, holding the objects of the cluster,
+	// e.g. nodes, pods, namespaces, leases, resourcequotas and limitranges. Lists are flattened.
+	ObjectPaths []string
+	// RequirementsFile is the yaml or json file of the MaxAcceptableReplicasRequest. It is read from stdin
+	// when empty or "-".
+	RequirementsFile string
+	// ConfigFile is the path of the predictor configuration file, which enables and configures plugins.
+	ConfigFile string
+	// CheckLeases keeps the Lease check of NodeHealth, which excludes all the nodes of a state captured
+	// longer ago than leaseMaxAgeSeconds.
+	CheckLeases bool
+}
+
+// Simulate predicts the max acceptable replicas of the requirements against the objects loaded from files,
+// the same as MaxAcceptableReplicas does against a live cluster.
+func Simulate(ctx context.Context, options SimulateOptions, registry framework.Registry, stdin io.Reader) (*AcceptableReplicas, error) {
+	config, err := LoadConfiguration(options.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	if !options.CheckLeases {
+		if err = disableLeaseCheck(config); err != nil {
+			return nil, err
+		}
+	}
+
+	var objects []runtime.Object
+	for _, path := range options.ObjectPaths {
+		loaded, err := loadObjects(path)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, loaded...)
+	}
+	require, err := loadRequirements(options.RequirementsFile, stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	return predictObjects(ctx, config, registry, objects, nil, require)
+}
+
+// predictObjects predicts against a fake cluster holding the objects, with the replicas assumed to be
+// placed on the nodes, keyed by node name.
+func predictObjects(ctx context.Context, config *PredictorConfiguration, registry framework.Registry,
+	objects []runtime.Object, assumed map[string][]framework.AssumedReplicas, require *MaxAcceptableReplicasRequest) (*AcceptableReplicas, error) {
+	objects, err := dedupObjects(objects)
+	if err != nil {
+		return nil, err
+	}
+	client := fake.NewSimpleClientset(objects...)
+	factory := informers.NewSharedInformerFactory(client, 0)
+	nodeCache := predictorcache.New()
+	nodeInformer := factory.Core().V1().Nodes()
+	nodeInformer.Informer().AddEventHandler(nodeCache.NodeEventHandler())
+	podInformer := factory.Core().V1().Pods()
+	podInformer.Informer().AddEventHandler(nodeCache.PodEventHandler())
+	p := &PredictorServer{
+		k8sClient:           client,
+		factory:             factory,
+		nodeInformer:        nodeInformer,
+		podInformer:         podInformer,
+		priorityClassLister: factory.Scheduling().V1().PriorityClasses().Lister(),
+		limitRangeLister:    factory.Core().V1().LimitRanges().Lister(),
+		cache:               nodeCache,
+	}
+	p.framework, err = framework.NewFramework(registry, config.Plugins, config.PluginConfig, p)
+	if err != nil {
+		return nil, err
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	for informerType, synced := range factory.WaitForCacheSync(stopCh) {
+		if !synced {
+			return nil, fmt.Errorf("cache of %v is not synced", informerType)
+		}
+	}
+
+	if statusErr := p.resolvePriority(require); statusErr != nil {
+		return nil, statusErr
+	}
+	if statusErr := p.applyLimitRanges(require); statusErr != nil {
+		return nil, statusErr
+	}
+	result, err := p.framework.Predict(ctx, newRequirements(require), p.cache.Snapshot().WithAssumedReplicas(assumed))
+	if err != nil {
+		return nil, err
+	}
+	return newAcceptableReplicas(result), nil
+}
+
+// dedupObjects returns the objects with only the last one of the same kind, namespace and name kept, in
+// the place of the first one, e.g. when the loaded files overlap, as the fake cluster holds an object once.
+func dedupObjects(objects []runtime.Object) ([]runtime.Object, error) {
+	type objectKey struct {
+		kind            schema.GroupKind
+		namespace, name string
+	}
+	indexes := make(map[objectKey]int, len(objects))
+	deduped := make([]runtime.Object, 0, len(objects))
+	for _, obj := range objects {
+		kinds, _, err := scheme.Scheme.ObjectKinds(obj)
+		if err != nil {
+			return nil, err
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		key := objectKey{kind: kinds[0].GroupKind(), namespace: accessor.GetNamespace(), name: accessor.GetName()}
+		if i, ok := indexes[key]; ok {
+			klog.Warningf("replace duplicate %s %s/%s with the one loaded later", key.kind.Kind, key.namespace, key.name)
+			deduped[i] = obj
+			continue
+		}
+		indexes[key] = len(deduped)
+		deduped = append(deduped, obj)
+	}
+	return deduped, nil
+}
+
+// disableLeaseCheck sets leaseMaxAgeSeconds of NodeHealth to 0, keeping its other args.
+func disableLeaseCheck(config *PredictorConfiguration) error {
+	for i := range config.PluginConfig {
+		pc := &config.PluginConfig[i]
+		if pc.Name != plugins.NodeHealthName {
+			continue
+		}
+		var args plugins.NodeHealthArgs
+		if len(pc.Args) != 0 {
+			if err := json.Unmarshal(pc.Args, &args); err != nil {
+				return fmt.Errorf("error of decode args of %s : %v", pc.Name, err)
+			}
+		}
+		args.LeaseMaxAgeSeconds = 0
+		raw, err := json.Marshal(args)
+		if err != nil {
+			return err
+		}
+		pc.Args = raw
+	}
+	return nil
+}
+
+// loadObjects loads the objects from a yaml or json file, or from all such files under a directory.
+func loadObjects(path string) ([]runtime.Object, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error of load objects : %v", err)
+	}
+	if !info.IsDir() {
+		return loadObjectFile(path)
+	}
+
+	var objects []runtime.Object
+	err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		loaded, err := loadObjectFile(file)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, loaded...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error of load objects under %s : %v", path, err)
+	}
+	return objects, nil
+}
+
+// loadObjectFile loads the objects of all the documents in a yaml or json file.
+func loadObjectFile(file string) ([]runtime.Object, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []runtime.Object
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var raw json.RawMessage
+		if err = decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("error of decode %s : %v", file, err)
+		}
+		if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(raw, []byte("null")) {
+			continue
+		}
+		decoded, err := decodeObjects(raw)
+		if err != nil {
+			return nil, fmt.Errorf("error of decode %s : %v", file, err)
+		}
+		objects = append(objects, decoded...)
+	}
+}
+
+// decodeObjects decodes a json object, flattening lists such as the output of "kubectl get -o yaml".
+// Documents without a kind and objects of the kinds unknown to client-go are skipped.
+func decodeObjects(raw []byte) ([]runtime.Object, error) {
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.Kind == "" {
+		klog.Warningf("skip document without kind")
+		return nil, nil
+	}
+	if strings.HasSuffix(typeMeta.Kind, "List") {
+		var list struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+		var objects []runtime.Object
+		for _, item := range list.Items {
+			// the items of a typed list, e.g. a NodeList, may leave out their kind
+			item, err := withTypeMeta(item, typeMeta)
+			if err != nil {
+				return nil, err
+			}
+			decoded, err := decodeObjects(item)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, decoded...)
+		}
+		return objects, nil
+	}
+
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(raw, nil, nil)
+	if err != nil {
+		if runtime.IsNotRegisteredError(err) {
+			klog.Warningf("skip object of unknown kind %s %s", typeMeta.APIVersion, typeMeta.Kind)
+			return nil, nil
+		}
+		return nil, err
+	}
+	return []runtime.Object{obj}, nil
+}
+
+// withTypeMeta fills in the kind of a list item from the kind of a typed list.
+func withTypeMeta(item []byte, listMeta metav1.TypeMeta) ([]byte, error) {
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(item, &typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.Kind != "" || listMeta.Kind == "List" {
+		return item, nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(item, &obj); err != nil {
+		return nil, err
+	}
+	obj["apiVersion"] = listMeta.APIVersion
+	obj["kind"] = strings.TrimSuffix(listMeta.Kind, "List")
+	return json.Marshal(obj)
+}
+
+// loadRequirements loads the request from a yaml or json file, or from stdin.
+func loadRequirements(file string, stdin io.Reader) (*MaxAcceptableReplicasRequest, error) {
+	var data []byte
+	var err error
+	if file == "" || file == "-" {
+		data, err = ioutil.ReadAll(stdin)
+	} else {
+		data, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error of read requirements : %v", err)
+	}
+
+	var require MaxAcceptableReplicasRequest
+	if err = yaml.Unmarshal(data, &require); err != nil {
+		return nil, fmt.Errorf("error of decode requirements : %v", err)
+	}
+	return &require, nil
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const simulateNodes = `apiVersion: v1
+kind: NodeList
+items:
+- metadata:
+    name: node-1
+  status:
+    allocatable: {cpu: "4", pods: "110"}
+    conditions: [{type: Ready, status: "True"}]
+- metadata:
+    name: node-2
+  spec:
+    unschedulable: true
+  status:
+    allocatable: {cpu: "4", pods: "110"}
+`
+
+const simulatePods = `apiVersion: v1
+kind: Pod
+metadata: {namespace: default, name: pod-1}
+spec:
+  nodeName: node-1
+  containers: [{name: app, resources: {requests: {cpu: "1"}}}]
+status: {phase: Running}
+---
+apiVersion: v1
+kind: Pod
+metadata: {namespace: default, name: pod-2}
+spec:
+  nodeName: node-1
+  containers: [{name: app, resources: {requests: {cpu: "2"}}}]
+status: {phase: Succeeded}
+---
+apiVersion: apps.clusternet.io/v1alpha1
+kind: Subscription
+metadata: {namespace: default, name: unknown}
+`
+
+// simulateNodeUpdate is node-2 captured again, once it is schedulable
+const simulateNodeUpdate = `apiVersion: v1
+kind: Node
+metadata:
+  name: node-2
+status:
+  allocatable: {cpu: "4", pods: "110"}
+  conditions: [{type: Ready, status: "True"}]
+`
+
+const simulateLease = `{"apiVersion": "coordination.k8s.io/v1", "kind": "Lease",
+  "metadata": {"namespace": "kube-node-lease", "name": "node-1"},
+  "spec": {"renewTime": "2022-01-01T00:00:00.000000Z"}}`
+
+func TestSimulate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "pods"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		"nodes.yaml":       simulateNodes,
+		"pods/pods.yml":    simulatePods,
+		"pods/lease.json":  simulateLease,
+		"pods/README.md":   "not an object",
+		"requirements.yml": "resources: {requests: {cpu: 500m}}",
+		"config.yaml":      "pluginConfig:\n  - name: NodeHealth\n    args: {leaseMaxAgeSeconds: 40}\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the files outside of dir are not loaded with it
+	updateFile := filepath.Join(t.TempDir(), "node-2.yaml")
+	if err := ioutil.WriteFile(updateFile, []byte(simulateNodeUpdate), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		options      SimulateOptions
+		stdin        string
+		wantReplicas int64
+		wantNodes    []NodeReplicas
+		wantRejected []string
+		wantErr      bool
+	}{
+		{
+			name: "requirements from a file",
+			options: SimulateOptions{
+				ObjectPaths:      []string{filepath.Join(dir, "nodes.yaml"), filepath.Join(dir, "pods")},
+				RequirementsFile: filepath.Join(dir, "requirements.yml"),
+			},
+			wantReplicas: 6,
+			wantNodes:    []NodeReplicas{{Name: "node-1", Replicas: 6, LimitedBy: "cpu"}},
+			wantRejected: []string{"node-2"},
+		},
+		{
+			name:         "requirements from stdin",
+			options:      SimulateOptions{ObjectPaths: []string{dir}},
+			stdin:        `{"resources": {"requests": {"cpu": "1"}}}`,
+			wantReplicas: 3,
+			wantNodes:    []NodeReplicas{{Name: "node-1", Replicas: 3, LimitedBy: "cpu"}},
+			wantRejected: []string{"node-2"},
+		},
+		{
+			name:         "duplicate objects",
+			options:      SimulateOptions{ObjectPaths: []string{dir, filepath.Join(dir, "nodes.yaml"), updateFile}},
+			stdin:        `{"resources": {"requests": {"cpu": "1"}}}`,
+			wantReplicas: 7,
+			wantNodes: []NodeReplicas{
+				{Name: "node-1", Replicas: 3, LimitedBy: "cpu"},
+				{Name: "node-2", Replicas: 4, LimitedBy: "cpu"},
+			},
+		},
+		{
+			name:         "stale leases",
+			options:      SimulateOptions{ObjectPaths: []string{dir}, ConfigFile: filepath.Join(dir, "config.yaml"), CheckLeases: true},
+			stdin:        `{"resources": {"requests": {"cpu": "1"}}}`,
+			wantRejected: []string{"node-1", "node-2"},
+		},
+		{
+			name:    "missing objects",
+			options: SimulateOptions{ObjectPaths: []string{filepath.Join(dir, "missing")}},
+			stdin:   `{}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Simulate(context.TODO(), tt.options, NewRegistry(), strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Simulate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.MaxAcceptableReplicas != tt.wantReplicas {
+				t.Errorf("Simulate() replicas = %d, want %d", got.MaxAcceptableReplicas, tt.wantReplicas)
+			}
+			if !reflect.DeepEqual(got.Nodes, tt.wantNodes) {
+				t.Errorf("Simulate() nodes = %+v, want %+v", got.Nodes, tt.wantNodes)
+			}
+			var rejected []string
+			for _, node := range got.RejectedNodes {
+				rejected = append(rejected, node.Name)
+			}
+			if !reflect.DeepEqual(rejected, tt.wantRejected) {
+				t.Errorf("Simulate() rejected nodes = %v, want %v", rejected, tt.wantRejected)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/clusternet/sample-controller/pkg/predictor/framework"
+)
+
+// TKECloudHSSDName is the name of the TKECloudHSSD plugin.
+const TKECloudHSSDName = "TKECloudHSSD"
+
+// TKECloudHSSD is an example filter plugin, which excludes the nodes that
+// run out of cloud hssd disks in a TKE cluster.
+type TKECloudHSSD struct{}
+
+var _ framework.FilterPlugin = &TKECloudHSSD{}
+
+// NewTKECloudHSSD returns a TKECloudHSSD plugin.
+func NewTKECloudHSSD(_ json.RawMessage, _ framework.Handle) (framework.Plugin, error) {
+	return &TKECloudHSSD{}, nil
+}
+
+// Name returns name of the plugin.
+func (pl *TKECloudHSSD) Name() string {
+	return TKECloudHSSDName
+}
+
+// Filter checks the node annotation that represents whether cloud hssd is enough.
+func (pl *TKECloudHSSD) Filter(_ context.Context, _ *framework.CycleState, _ *framework.Requirements, nodeInfo *framework.NodeInfo) *framework.Status {
+	if nodeInfo.Node().Annotations["tke.cloud.tencent.com/res-cloud-hssd"] == "false" {
+		return framework.NewStatus(framework.Unschedulable, "node(s) had no cloud hssd available")
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2022 The Clusternet Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predictor
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsapi "github.com/clusternet/clusternet/pkg/apis/apps/v1alpha1"
+)
+
+// MaxAcceptableReplicasRequest is the body of a max acceptable replicas request. It is a
+// ReplicaRequirements, optionally extended with what is needed to honor the required pod
+// anti-affinity and the topology spread constraints of the workload.
+type MaxAcceptableReplicasRequest struct {
+	appsapi.ReplicaRequirements `json:",inline"`
+
+	// Namespace is the namespace of the workload. Pod anti-affinity terms without namespaces
+	// select the pods of this namespace.
+	Namespace string `json:"namespace,omitempty"`
+	// PodLabels are the labels of the pods of the workload.
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+	// TopologySpreadConstraints are the topology spread constraints of the pods of the workload.
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// Priority or PriorityClassName is the priority of the pods of the workload. With either of them,
+	// the replicas are also predicted as if the pods of lower priority were preempted, unless
+	// PreemptionPolicy, which defaults to that of the PriorityClass, is Never.
+	Priority          *int32                   `json:"priority,omitempty"`
+	PriorityClassName string                   `json:"priorityClassName,omitempty"`
+	PreemptionPolicy  *corev1.PreemptionPolicy `json:"preemptionPolicy,omitempty"`
+
+	// ReservationID reserves the predicted replicas under the id, so that later predictions do not offer
+	// the same capacity again until the reservation expires, is released, or is taken up by the bound pods
+	// of the workload, which are matched by Namespace and PodLabels. A later prediction with the same id
+	// replaces the reservation, and does not see it. It requires reservations to be enabled.
+	ReservationID string `json:"reservationID,omitempty"`
+}
+
+// UnschedulableReplicasRequest identifies a workload whose pending replicas should be counted.
+type UnschedulableReplicasRequest struct {
+	metav1.GroupVersionKind `json:",inline"`
+
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// LabelSelector selects the pods of the workload. When it is empty, pods are
+	// only matched by their controller owner reference.
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+}
+
+// UnschedulableReplicasResponse is the response of an unschedulable replicas request.
+type UnschedulableReplicasResponse struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// UnschedulableReplicas is the number of replicas stuck in Pending because
+	// no node in the cluster can accept them.
+	UnschedulableReplicas int32 `json:"unschedulableReplicas"`
+}
+
+// PredictorAPIVersion is the version of the structured responses of the predictor.
+const PredictorAPIVersion = "predictor.clusternet.io/v1alpha1"
+
+// AcceptableReplicas is the structured response of a max acceptable replicas request.
+type AcceptableReplicas struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	// MaxAcceptableReplicas is the total replicas the cluster could accept without preempting any pod,
+	// or the reserved replicas if a reservation is made.
+	MaxAcceptableReplicas int64 `json:"maxAcceptableReplicas"`
+	// Nodes are the nodes that passed all filters, sorted by name.
+	Nodes []NodeReplicas `json:"nodes,omitempty"`
+	// RejectedNodes are the nodes that were filtered out, sorted by name.
+	RejectedNodes []RejectedNode `json:"rejectedNodes,omitempty"`
+	// ClusterCap is the cluster-level cap that bounded MaxAcceptableReplicas, if any.
+	ClusterCap *ClusterCap `json:"clusterCap,omitempty"`
+	// Reservation is the capacity reserved for the request, if it has a reservation id.
+	Reservation *Reservation `json:"reservation,omitempty"`
+	// WithPreemption is the prediction when the pods of lower priority are preempted, if the request
+	// has a priority that could preempt.
+	WithPreemption *PreemptionReplicas `json:"withPreemption,omitempty"`
+}
+
+// PreemptionReplicas is the replicas the cluster could accept by preempting the pods of lower priority.
+type PreemptionReplicas struct {
+	MaxAcceptableReplicas int64          `json:"maxAcceptableReplicas"`
+	Nodes                 []NodeReplicas `json:"nodes,omitempty"`
+	ClusterCap            *ClusterCap    `json:"clusterCap,omitempty"`
+}
+
+// NodeReplicas is the replicas a node could hold.
+type NodeReplicas struct {
+	Name     string `json:"name"`
+	Replicas int64  `json:"replicas"`
+	// LimitedBy is the resource, or the plugin, that bounds the replicas of the node.
+	LimitedBy string `json:"limitedBy,omitempty"`
+}
+
+// RejectedNode is a node that could not hold any replica.
+type RejectedNode struct {
+	Name    string   `json:"name"`
+	Plugin  string   `json:"plugin"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// ClusterCap is a cluster-level cap applied to the total replicas.
+type ClusterCap struct {
+	Plugin   string `json:"plugin"`
+	Replicas int64  `json:"replicas"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Reservation is the replicas reserved by a prediction.
+type Reservation struct {
+	ID string `json:"id"`
+	// Replicas are the reserved replicas, which are the predicted replicas placed over the nodes.
+	Replicas int64 `json:"replicas"`
+	// ExpirationTime is when the reservation is released at the latest.
+	ExpirationTime metav1.Time `json:"expirationTime"`
+}
+
+// ReleaseRequest is the body of a request releasing a reservation.
+type ReleaseRequest struct {
+	ID string `json:"id"`
+}
+
+// ReleaseResponse is the response of a request releasing a reservation.
+type ReleaseResponse struct {
+	ID string `json:"id"`
+	// Released tells whether the reservation was active. Releasing an expired or unknown reservation
+	// is not an error.
+	Released bool `json:"released"`
+}
+
+// ClusterList is the response of /clusters, when the predictor serves multiple clusters.
+type ClusterList struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	// Items are the clusters sorted by name.
+	Items []ClusterStatus `json:"items"`
+}
+
+// ClusterStatus tells whether a cluster is ready to be predicted against.
+type ClusterStatus struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+	// Reason is why the cluster is not ready.
+	Reason string `json:"reason,omitempty"`
+}
+
+// BatchAcceptRequest is the body of a batch max acceptable replicas request.
+type BatchAcceptRequest struct {
+	// Joint predicts the items in order as if the replicas of the earlier items had been placed,
+	// so that the capacity they use is not offered again to the later items.
+	// Otherwise every item is predicted independently.
+	Joint bool `json:"joint,omitempty"`
+	// Items are the requirements to predict.
+	Items []BatchAcceptItem `json:"items"`
+}
+
+// BatchAcceptItem is an item of a batch request.
+type BatchAcceptItem struct {
+	// ID identifies the item in the response, e.g. the name of a feed. It is optional.
+	ID string `json:"id,omitempty"`
+	// Replicas are the replicas the item wants. A joint item, or one with a reservation, takes up as many
+	// of its predicted replicas, or all of them if it is not set, i.e. the whole capacity predicted for it.
+	Replicas *int64 `json:"replicas,omitempty"`
+
+	MaxAcceptableReplicasRequest `json:",inline"`
+}
+
+// BatchAcceptableReplicas is the response of a batch max acceptable replicas request.
+type BatchAcceptableReplicas struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	// Items are the predictions in the order of the requested items.
+	Items []BatchAcceptableReplicasItem `json:"items"`
+}
+
+// BatchAcceptableReplicasItem is the prediction of an item of a batch request.
+type BatchAcceptableReplicasItem struct {
+	ID string `json:"id,omitempty"`
+	// Result is the prediction of the item, which is missing if the item failed.
+	Result *AcceptableReplicas `json:"result,omitempty"`
+	// Error is why the item failed.
+	Error string `json:"error,omitempty"`
+}